@@ -1,27 +1,48 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/gen2brain/beeep"
-	"io"
 	"net"
-	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"att/client"
+	"att/ipc"
+	"att/queue"
+	"att/store"
+	"att/utils"
 )
 
 var pipePath string
+var baseURL string
+var baseURLFlag string
+var notifications utils.NotificationSettings
+
 const iconPath = "./assets/ico.png"
 
+var pidFile string
+var httpAddr string
+var startedAt time.Time
+
 func init() {
-	if runtime.GOOS == "windows" {
-		pipePath = `\\.\pipe\attd`
-	} else {
-		pipePath = "/tmp/attd"
+	if path, err := utils.SocketPath(); err == nil {
+		pipePath = path
+	}
+	if path, err := utils.PIDFilePath(); err == nil {
+		pidFile = path
 	}
 }
 
@@ -29,21 +50,101 @@ func main() {
 	// Define the pipePath flag
 	var pipePathFlag string
 	flag.StringVar(&pipePathFlag, "pipe-path", pipePath, "set the path for the pipe")
+	flag.StringVar(&baseURLFlag, "base-url", "", "set the Hack Hour API base URL (overrides ATT_BASE_URL and the config)")
+	flag.DurationVar(&utils.RequestTimeout, "timeout", utils.DefaultRequestTimeout, "set the timeout of a single API request")
+	flag.IntVar(&utils.MaxRetries, "retries", utils.DefaultMaxRetries, "set the number of retries for failed API requests")
+	flag.StringVar(&utils.Profile, "profile", "", "set the att config profile to use (overrides ATT_PROFILE)")
+	flag.StringVar(&httpAddr, "http", "", "serve the HTTP API on this localhost address (overrides daemon.http of the config)")
+	flag.StringVar(&utils.ConfigPath, "config", "", "set the path of the att config file")
+	flag.StringVar(&pidFile, "pid-file", pidFile, "set the path of the pid file")
 	flag.Parse()
 
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		logf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	for _, warning := range config.Warnings {
+		logf("Config warning: %s\n", warning)
+	}
+	notifications = config.Notifications
+
+	baseURL, err = utils.ResolveBaseURL(baseURLFlag)
+	if err != nil {
+		logf("Failed to resolve API base URL: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Requests use the credentials of the active profile unless they select another
+	configData, err := utils.LoadConfigData()
+	if err != nil {
+		logf("Failed to load credentials: %v\n", err)
+		os.Exit(1)
+	}
+	addSecret(configData["api-token"])
+	if configData["api-token"] == "" || configData["slack-id"] == "" {
+		logf("Profile %s has no API token or Slack ID, requests have to select a configured profile or carry slack_id and api_key\n", config.ActiveProfile())
+	}
+
+	// The socket path and HTTP address of the config apply unless the flags are provided
+	pipePathSet, httpSet := false, false
+	flag.Visit(func(f *flag.Flag) {
+		pipePathSet = pipePathSet || f.Name == "pipe-path"
+		httpSet = httpSet || f.Name == "http"
+	})
+	if !pipePathSet && config.Daemon.Socket != "" {
+		pipePathFlag = config.Daemon.Socket
+	}
+	if !httpSet {
+		httpAddr = config.Daemon.HTTP
+	}
+
 	// If the flag is provided, update the pipePath
 	if pipePathFlag != "" {
 		pipePath = pipePathFlag
 	}
 
-	fmt.Printf("Starting daemon with pipe path: %s\n", pipePath)
-	notify("attd", "Arcade Time Tracker Daemon", fmt.Sprintf("Starting daemon with pipe path: %s", pipePath))
+	logf("Starting daemon with pipe path: %s\n", pipePath)
+	logf("Using API base URL: %s\n", baseURL)
+	sendNotification(ipc.Event{}, "Arcade Time Tracker Daemon", fmt.Sprintf("Starting daemon with pipe path: %s", pipePath))
+
+	// The socket directory is private to the user
+	if err := os.MkdirAll(filepath.Dir(pipePath), 0700); err != nil {
+		logf("Failed to create the socket directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Refuse to take over the socket of a running daemon
+	if c, err := ipc.Dial(pipePath, time.Second); err == nil {
+		c.Close()
+		logf("Another daemon is already listening on %s\n", pipePath)
+		os.Exit(1)
+	}
+
+	// The HTTP API is optional and only listens on localhost
+	var httpListener net.Listener
+	if httpAddr != "" {
+		if err := utils.ValidateHTTPAddr(httpAddr); err != nil {
+			logf("Invalid HTTP API address: %v\n", err)
+			os.Exit(1)
+		}
+		// Create the token before serving, requests only read it
+		if _, err := utils.HTTPToken(); err != nil {
+			logf("Failed to create the HTTP API token: %v\n", err)
+			os.Exit(1)
+		}
+		if httpListener, err = net.Listen("tcp", httpAddr); err != nil {
+			logf("Failed to listen on %s: %v\n", httpAddr, err)
+			os.Exit(1)
+		}
+		httpAddr = httpListener.Addr().String()
+	}
 
 	// Ensure the pipe file does not already exist (Unix-like systems)
 	if runtime.GOOS != "windows" {
 		if _, err := os.Stat(pipePath); err == nil {
-			fmt.Printf("Pipe file already exists, removing: %s\n", pipePath)
-			notify("attd", "Arcade Time Tracker Daemon", fmt.Sprintf("Pipe file already exists, removing: %s", pipePath))
+			logf("Pipe file already exists, removing: %s\n", pipePath)
+			sendNotification(ipc.Event{}, "Arcade Time Tracker Daemon", fmt.Sprintf("Pipe file already exists, removing: %s", pipePath))
 			os.Remove(pipePath)
 		}
 	}
@@ -51,8 +152,8 @@ func main() {
 	// Create a Named Pipe listener
 	listener, err := net.Listen("unix", pipePath)
 	if err != nil {
-		fmt.Printf("Failed to listen on pipe: %v\n", err)
-		notify("attd", "Arcade Time Tracker Daemon", fmt.Sprintf("Failed to listen on pipe: %v", err))
+		logf("Failed to listen on pipe: %v\n", err)
+		sendNotification(ipc.Event{}, "Arcade Time Tracker Daemon", fmt.Sprintf("Failed to listen on pipe: %v", err))
 		return
 	}
 	defer listener.Close()
@@ -62,286 +163,305 @@ func main() {
 		defer os.Remove(pipePath)
 	}
 
-	fmt.Println("Daemon started and listening on", pipePath)
-	notify("attd", "Arcade Time Tracker Daemon", fmt.Sprintf("Daemon started and listening on %s", pipePath))
+	// The pid file tells "att daemon stop" which process to signal
+	if err := utils.WriteFileAtomic(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0600); err != nil {
+		logf("Failed to write the pid file: %v\n", err)
+	} else {
+		defer os.Remove(pidFile)
+	}
+
+	// Stop accepting connections on SIGINT or SIGTERM so the deferred cleanup runs
+	stopping := make(chan os.Signal, 1)
+	signal.Notify(stopping, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-stopping
+		logf("Received %s, stopping\n", sig)
+		listener.Close()
+	}()
+
+	// Sessions tracked before a restart get their notifications again
+	restoreTrackers()
+
+	if httpListener != nil {
+		tokenPath, _ := utils.HTTPTokenPath()
+		logf("Serving the HTTP API on http://%s, its bearer token is in %s\n", httpAddr, tokenPath)
+		go func() {
+			if err := serveHTTP(httpListener); err != nil {
+				logf("HTTP API stopped: %v\n", err)
+			}
+		}()
+	}
+
+	startedAt = time.Now()
+	logln("Daemon started and listening on", pipePath)
+	sendNotification(ipc.Event{}, "Arcade Time Tracker Daemon", fmt.Sprintf("Daemon started and listening on %s", pipePath))
 
 	for {
 		// Accept new connections
 		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			logln("Daemon stopped")
+			return
+		}
 		if err != nil {
-			fmt.Printf("Failed to accept connection: %v\n", err)
+			logf("Failed to accept connection: %v\n", err)
 			continue
 		}
 
-		fmt.Println("New connection accepted")
+		logln("New connection accepted")
 
 		// Handle the connection in a new goroutine
 		go handleConnection(conn)
 	}
 }
 
+// writeTimeout bounds a single write to a client, so a client that stops
+// reading can not block the events written to it forever
+const writeTimeout = 10 * time.Second
+
+// connection is a client connection. After a subscribe request, events are
+// written to it while the requests on it are still answered.
+type connection struct {
+	conn         net.Conn
+	lock         sync.Mutex
+	encoder      *json.Encoder
+	subscription chan ipc.Event
+}
+
+// write sends a response within writeTimeout, safe for concurrent use
+func (c *connection) write(response *ipc.Response) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.encoder.Encode(response)
+}
+
+// handleConnection answers the requests of a connection, one per line,
+// until the client closes it
 func handleConnection(conn net.Conn) {
 	defer conn.Close()
-	fmt.Println("Handling new connection")
-
-	// Read the command from the client
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
-	if err != nil {
-		fmt.Printf("Failed to read from connection: %v\n", err)
-		return
-	}
+	logln("Handling new connection")
 
-	input := string(buf[:n])
-	fmt.Printf("Received input: %s\n", input)
+	c := &connection{conn: conn, encoder: json.NewEncoder(conn)}
+	defer func() {
+		if c.subscription != nil {
+			unsubscribe(c.subscription)
+		}
+	}()
 
-	// Parse the input as JSON
-	var command struct {
-		Command string                 `json:"command"`
-		Data    map[string]interface{} `json:"data"`
-	}
-	err = json.Unmarshal([]byte(input), &command)
-	if err != nil {
-		fmt.Printf("Failed to parse input as JSON: %v\n", err)
-		conn.Write([]byte(fmt.Sprintf("Failed to parse input as JSON: %v\n", err)))
-		return
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), ipc.MaxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := handleRequest(c, line); err != nil {
+			logf("Failed to write to connection: %v\n", err)
+			return
+		}
 	}
-
-	// Route based on the command
-	switch command.Command {
-	case "start":
-		handleStartCommand(conn, command.Data)
-	case "track":
-		handleTrackCommand(conn, command.Data)
-	default:
-		conn.Write([]byte("Unknown command\n"))
+	if err := scanner.Err(); err != nil {
+		logf("Failed to read from connection: %v\n", err)
+		c.write(ipc.NewResponse("", nil, ipc.Errorf(ipc.CodeBadRequest, "unable to read request: %v", err)))
 	}
 }
 
-func handleStartCommand(conn net.Conn, data map[string]interface{}) {
-	work, ok := data["work"].(string)
-	if !ok {
-		conn.Write([]byte("Invalid or missing 'work' value\n"))
-		return
+// handleRequest parses a request, routes it to its handler and writes the
+// response
+func handleRequest(c *connection, line []byte) error {
+	var request ipc.Request
+	if err := json.Unmarshal(line, &request); err != nil {
+		return c.write(ipc.NewResponse("", nil, ipc.Errorf(ipc.CodeBadRequest, "invalid request: %v", err)))
 	}
-	slackID, ok := data["slack_id"].(string)
-	if !ok {
-		conn.Write([]byte("Invalid or missing 'slack_id' value\n"))
-		return
+	if request.Version < 0 || request.Version > ipc.Version {
+		return c.write(ipc.NewResponse(request.ID, nil, ipc.Errorf(ipc.CodeUnsupportedVersion, "unsupported protocol version %d (attd speaks %d)", request.Version, ipc.Version)))
 	}
-	apiKey, ok := data["api_key"].(string)
-	if !ok {
-		conn.Write([]byte("Invalid or missing 'api_key' value\n"))
-		return
+	if request.Command == "subscribe" {
+		logf("Received subscribe request %q\n", request.ID)
+		return c.subscribe(request.ID)
 	}
-
-	// Perform the API POST request to start a new session
-	respStatus, respBody := postToAPI(work, slackID, apiKey)
-	response := fmt.Sprintf("Response Status: %s\nResponse Body: %s\n", respStatus, respBody)
-	fmt.Println("API request made, sending response back to sender")
-
-	_, err := conn.Write([]byte(response))
-	if err != nil {
-		fmt.Printf("Failed to write to connection: %v\n", err)
+	handler, ok := commands[request.Command]
+	if !ok {
+		return c.write(ipc.NewResponse(request.ID, nil, ipc.Errorf(ipc.CodeUnknownCommand, "unknown command %q", request.Command)))
 	}
 
-	// Send a push notification based on the response
-	handleNotification(respBody, work, time.Time{})
-	time.Sleep(1 * time.Second) // Adding delay to ensure response is sent before the client closes the connection
+	logf("Received %s request %q\n", request.Command, request.ID)
+	result, err := execute(request.Command, handler, request.Data)
+	return c.write(ipc.NewResponse(request.ID, result, err))
 }
 
-func handleTrackCommand(conn net.Conn, data map[string]interface{}) {
-	slackID, ok := data["slack_id"].(string)
-	if !ok {
-		conn.Write([]byte("Invalid or missing 'slack_id' value\n"))
-		return
+// execute runs a command for a socket or HTTP request. Errors are returned
+// as an *ipc.Error with the secrets redacted, and API errors are published.
+func execute(command string, handler commandHandler, data json.RawMessage) (interface{}, error) {
+	result, err := handler(data)
+	if err == nil {
+		return result, nil
 	}
-	apiKey, ok := data["api_key"].(string)
-	if !ok {
-		conn.Write([]byte("Invalid or missing 'api_key' value\n"))
-		return
+	e := ipc.ErrorFrom(err)
+	e = &ipc.Error{Code: e.Code, Message: redactSecrets(e.Message)}
+	logf("%s request failed: %v\n", command, e.Message)
+	if isAPIError(e) {
+		publishAPIError("", command, e)
 	}
+	return nil, e
+}
 
-	// Fetch the latest session information
-	endTime, createdAt, err := getSessionTimes(slackID, apiKey)
-	if err != nil {
-		fmt.Printf("Failed to get session times: %v\n", err)
-		conn.Write([]byte(fmt.Sprintf("Failed to get session times: %v\n", err)))
-		return
+// subscribe answers a subscribe request and then streams the events to the
+// connection with the ID of the request, until either side closes it
+func (c *connection) subscribe(id string) error {
+	if c.subscription != nil {
+		return c.write(ipc.NewResponse(id, nil, ipc.Errorf(ipc.CodeBadRequest, "the connection is already subscribed")))
+	}
+	ch, seq := subscribe()
+	c.subscription = ch
+	if err := c.write(ipc.NewResponse(id, ipc.SubscribeResult{Seq: seq}, nil)); err != nil {
+		return err
 	}
 
-	fmt.Printf("Tracking started from: %s\n", createdAt)
-
-	// Start the tracking system with notifications
-	go setupNotificationsFrom(createdAt, endTime)
-
-	conn.Write([]byte(fmt.Sprintf("Tracking started with end time: %s\n", endTime)))
+	go func() {
+		for e := range ch {
+			e := e
+			if err := c.write(&ipc.Response{Version: ipc.Version, ID: id, OK: true, Event: &e}); err != nil {
+				break
+			}
+		}
+		// The subscriber is gone or too slow, closing ends the request loop as well
+		c.conn.Close()
+	}()
+	return nil
 }
 
-func getSessionTimes(slackID, apiKey string) (time.Time, time.Time, error) {
-	url := fmt.Sprintf("https://hackhour.hackclub.com/api/session/%s", slackID)
-
-	// Create the HTTP request
-	req, _ := http.NewRequest("GET", url, nil)
-
-	// Set the Authorization header
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	// Perform the HTTP request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("failed to perform API request: %w", err)
+// isAPIError reports whether a failed request failed at the API, rather
+// than being rejected by attd
+func isAPIError(e *ipc.Error) bool {
+	switch e.Code {
+	case ipc.CodeBadRequest, ipc.CodeUnsupportedVersion, ipc.CodeUnknownCommand, ipc.CodeNotConfigured:
+		return false
 	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	respBody, _ := io.ReadAll(resp.Body)
+	return true
+}
 
-	// Parse the response
-	var response struct {
-		OK   bool `json:"ok"`
-		Data struct {
-			EndTime   string `json:"endTime"`
-			CreatedAt string `json:"createdAt"`
-		} `json:"data"`
+// decodeData decodes the data of a request, which may be empty
+func decodeData(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return nil
 	}
-	err = json.Unmarshal(respBody, &response)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse response: %w", err)
+	if err := json.Unmarshal(data, v); err != nil {
+		return ipc.Errorf(ipc.CodeBadRequest, "invalid request data: %v", err)
 	}
+	return nil
+}
 
-	// Check if the response is OK
-	if !response.OK {
-		return time.Time{}, time.Time{}, fmt.Errorf("API response not OK")
+// replayQueue replays the session actions the CLI queued while offline for
+// a profile, the one attd runs with when it is empty. The sessions started
+// by the replay are tracked and the ones cancelled or paused are no longer.
+func replayQueue(c *client.Client, profile string) ([]queue.Result, error) {
+	name := profile
+	if name == "" {
+		config, err := utils.LoadConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		name = config.ActiveProfile()
+	}
+	if !queue.Pending(name, c) {
+		return nil, nil
 	}
 
-	// Parse the endTime and createdAt
-	endTime, err := time.Parse(time.RFC3339, response.Data.EndTime)
-	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse endTime: %w", err)
+	results, err := queue.Flush(c, name)
+	replayed := false
+	for _, result := range results {
+		switch result.Status {
+		case queue.StatusDone:
+			replayed = true
+			logf("Replayed queued %s action #%d\n", result.Kind, result.ID)
+			if result.Kind == queue.Start {
+				publish(ipc.Event{Type: ipc.EventSessionStarted, SlackID: c.SlackID, Work: result.Work})
+			}
+		case queue.StatusFailed:
+			logf("Dropped queued %s action #%d: %s\n", result.Kind, result.ID, result.Error)
+			sendNotification(ipc.Event{SlackID: c.SlackID}, "Arcade Time Tracker", fmt.Sprintf("Queued %s failed: %s", result.Kind, result.Error))
+		case queue.StatusExpired:
+			logf("Dropped queued %s action #%d, it is older than %s\n", result.Kind, result.ID, queue.MaxAge)
+		}
+	}
+	if replayed {
+		// The API only toggles pauses, so the session tells what the replay left
+		if _, trackErr := trackSession(c, profile); errors.Is(trackErr, client.ErrConflict) {
+			stopTracker(c.SlackID)
+		}
 	}
-	createdAt, err := time.Parse(time.RFC3339, response.Data.CreatedAt)
 	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse createdAt: %w", err)
+		logf("Failed to replay queued actions: %v\n", err)
+		publishAPIError(c.SlackID, "flush", err)
 	}
-
-	return endTime, createdAt, nil
+	return results, err
 }
 
-func postToAPI(work, slackID, apiKey string) (string, string) {
-	url := fmt.Sprintf("https://hackhour.hackclub.com/api/start/%s", slackID)
-
-	// Prepare the JSON body
-	body := map[string]string{
-		"work": work,
+// syncStore refreshes the local session store shared with the CLI
+func syncStore(c *client.Client) {
+	if _, err := store.Sync(c); err != nil {
+		logf("Failed to sync the local store: %v\n", err)
 	}
-	jsonBody, _ := json.Marshal(body)
-
-	// Create the HTTP request
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-
-	// Set the Authorization header
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	// Perform the HTTP request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// newClient creates an API client for a request. The credentials are read
+// from the att config on every request, so changes apply without a restart.
+func newClient(creds credentials) (*client.Client, error) {
+	var configData map[string]string
+	var err error
+	if creds.Profile != "" {
+		configData, err = utils.LoadProfileData(creds.Profile)
+	} else {
+		configData, err = utils.LoadConfigData()
+	}
 	if err != nil {
-		return "Failed to perform API request", ""
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	respBody, _ := io.ReadAll(resp.Body)
-
-	// Return the response status and body
-	return resp.Status, string(respBody)
-}
-
-// precompute the notification times based on the start and end times
-func precomputeNotificationTimes(createdAt, endTime time.Time) []time.Time {
-	var notificationTimes []time.Time
-	currentTime := createdAt
-
-	for currentTime.Before(endTime) {
-		timeRemain := int(endTime.Sub(currentTime).Minutes())
-		if timeRemain <= 0 {
-			break
-		}
 
-		switch {
-		case timeRemain > 10 && timeRemain%20 == 0:
-			notificationTimes = append(notificationTimes, currentTime.Add(time.Duration(timeRemain)*time.Minute))
-		case timeRemain == 10:
-			notificationTimes = append(notificationTimes, currentTime.Add(10*time.Minute))
-		case timeRemain == 5:
-			notificationTimes = append(notificationTimes, currentTime.Add(5*time.Minute))
-		}
-
-		currentTime = currentTime.Add(time.Minute)
+	if creds.SlackID != "" {
+		configData["slack-id"] = creds.SlackID
 	}
-
-	return notificationTimes
-}
-
-
-// Modify the function `setupNotificationsFrom` to use precomputed times
-func setupNotificationsFrom(createdAt, endTime time.Time) {
-	notificationTimes := precomputeNotificationTimes(createdAt, endTime)
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case currentTime := <-ticker.C:
-			for _, nTime := range notificationTimes {
-				if currentTime.After(nTime) || currentTime.Equal(nTime) {
-					notify("attd", "Arcade Time Tracker", fmt.Sprintf("Notification for time: %s", nTime))
-				}
-			}
-			if currentTime.After(endTime) {
-				notify("attd", "Arcade Time Tracker", "You did it!")
-				return
-			}
-		}
+	if creds.APIKey != "" {
+		configData["api-token"] = creds.APIKey
+	}
+	baseURL := utils.ProfileBaseURL(baseURLFlag, configData)
+	if creds.BaseURL != "" && strings.TrimRight(creds.BaseURL, "/") != baseURL {
+		return nil, ipc.Errorf(ipc.CodeBaseURLMismatch, "attd uses %s instead of %s", baseURL, creds.BaseURL)
 	}
+	if creds.ExpectedSlackID != "" && creds.ExpectedSlackID != configData["slack-id"] {
+		return nil, ipc.Errorf(ipc.CodeSlackIDMismatch, "attd uses Slack ID %s instead of %s", configData["slack-id"], creds.ExpectedSlackID)
+	}
+	addSecret(configData["api-token"])
+	return client.New(baseURL, configData["api-token"], configData["slack-id"]), nil
 }
 
+func postToAPI(c *client.Client, work string) (*client.SessionAction, error) {
+	return c.Start(work)
+}
 
-func setupNotificationsFrom(createdAt, endTime time.Time) {
-	currentTime := createdAt
-
-	for currentTime.Before(endTime) {
-		timeRemain := int(endTime.Sub(currentTime).Minutes())
-		if timeRemain <= 0 {
-			break
-		}
-
-		switch {
-		case timeRemain > 10 && timeRemain%20 == 0:
-			notify("attd", "Arcade Time Tracker", fmt.Sprintf("You have %d minutes left!", timeRemain))
-			time.Sleep(20 * time.Minute)
-		case timeRemain == 10:
-			notify("attd", "Arcade Time Tracker", "Just 10 minutes left!")
-			time.Sleep(10 * time.Minute)
-		case timeRemain == 5:
-			notify("attd", "Arcade Time Tracker", "The last 5 minutes!")
-			time.Sleep(5 * time.Minute)
-		default:
-			time.Sleep(1 * time.Minute)
-		}
-
-		currentTime = time.Now()
+// handleNotification sends a push notification for the result of a start
+// request, e tells the user and the started session
+func handleNotification(e ipc.Event, work string, err error) {
+	if err != nil {
+		sendNotification(e, "Arcade Time Tracker", fmt.Sprintf("Failed to start session: %v", err))
+		return
 	}
-
-	notify("attd", "Arcade Time Tracker", "You did it!")
+	sendNotification(e, "Arcade Time Tracker", fmt.Sprintf("Session started: %s", work))
 }
 
-func notify(appName, title, message string) {
+// sendNotification sends a push notification and publishes it as an event
+// based on e, which tells the session it belongs to
+func sendNotification(e ipc.Event, title, message string) {
+	message = redactSecrets(message)
+	e.Type = ipc.EventNotification
+	e.Title = title
+	e.Message = message
+	publish(e)
+
 	err := beeep.Notify(title, message, iconPath)
 	if err != nil {
-		fmt.Printf("Failed to send notification: %v\n", err)
+		logf("Failed to send notification: %v\n", err)
 	}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"att/ipc"
+)
+
+func TestNewClientChecksExpectations(t *testing.T) {
+	c := setupMock(t, 0)
+
+	tests := []struct {
+		name     string
+		creds    credentials
+		wantCode string
+	}{
+		{"no expectations", credentials{}, ""},
+		{"matching", credentials{BaseURL: c.BaseURL + "/", ExpectedSlackID: "U1"}, ""},
+		{"other base URL", credentials{BaseURL: "http://example.invalid"}, ipc.CodeBaseURLMismatch},
+		{"other Slack ID", credentials{ExpectedSlackID: "U2"}, ipc.CodeSlackIDMismatch},
+		{"overridden Slack ID", credentials{SlackID: "U2", ExpectedSlackID: "U2"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newClient(tt.creds)
+			var ipcErr *ipc.Error
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Fatalf("newClient: %v", err)
+			case tt.wantCode == "":
+				if got.BaseURL != c.BaseURL {
+					t.Errorf("base URL = %s, want %s", got.BaseURL, c.BaseURL)
+				}
+			case !errors.As(err, &ipcErr) || ipcErr.Code != tt.wantCode:
+				t.Errorf("error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
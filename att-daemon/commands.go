@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"att/client"
+	"att/ipc"
+	"att/queue"
+	"att/store"
+	"att/utils"
+)
+
+// commandHandler answers the data of a request with a result or an error
+type commandHandler func(data json.RawMessage) (interface{}, error)
+
+// commands routes requests to their handler
+var commands = map[string]commandHandler{
+	"start":   handleStartCommand,
+	"pause":   handlePauseCommand,
+	"resume":  handleResumeCommand,
+	"cancel":  handleCancelCommand,
+	"track":   handleTrackCommand,
+	"flush":   handleFlushCommand,
+	"status":  handleStatusCommand,
+	"history": handleHistoryCommand,
+	"daemon":  handleDaemonCommand,
+	"session": handleFetch(func(c *client.Client) (interface{}, error) {
+		return c.Session()
+	}),
+	"stats": handleFetch(func(c *client.Client) (interface{}, error) {
+		return c.Stats()
+	}),
+	"goals": handleFetch(func(c *client.Client) (interface{}, error) {
+		return c.Goals()
+	}),
+}
+
+// credentials overrides the account of a request: the name of a profile
+// in the att config, and a Slack ID and API key replacing its own. Requests
+// without them use the profile attd runs with. BaseURL and ExpectedSlackID
+// are the base URL and Slack ID the client expects, the request is refused
+// when attd would use other ones.
+type credentials struct {
+	Profile         string `json:"profile,omitempty"`
+	SlackID         string `json:"slack_id,omitempty"`
+	APIKey          string `json:"api_key,omitempty"`
+	BaseURL         string `json:"base_url,omitempty"`
+	ExpectedSlackID string `json:"expected_slack_id,omitempty"`
+}
+
+// startData is the data of a start request
+type startData struct {
+	credentials
+	Work string `json:"work"`
+}
+
+// historyData is the data of a history request, see client.HistoryFilter.
+// since and until take the same values as the CLI flags.
+type historyData struct {
+	credentials
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+	Match string `json:"match,omitempty"`
+	Goal  string `json:"goal,omitempty"`
+	Sort  string `json:"sort,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+	Page  int    `json:"page,omitempty"`
+}
+
+// daemonResult is the result of a daemon request
+type daemonResult struct {
+	PID       int        `json:"pid"`
+	StartedAt time.Time  `json:"startedAt"`
+	Socket    string     `json:"socket"`
+	HTTP      string     `json:"http,omitempty"`
+	Trackers  []*tracker `json:"trackers"`
+}
+
+// flushResult is the result of a flush request
+type flushResult struct {
+	Replayed int            `json:"replayed"`
+	Results  []queue.Result `json:"results"`
+}
+
+// clientFor creates the API client of a request and replays the actions
+// the CLI queued while offline, so the session is up to date. It returns
+// the credentials of the request as well.
+func clientFor(data json.RawMessage) (*client.Client, credentials, error) {
+	var request credentials
+	if err := decodeData(data, &request); err != nil {
+		return nil, request, err
+	}
+	c, err := newClient(request)
+	if err != nil {
+		return nil, request, err
+	}
+	replayQueue(c, request.Profile)
+	return c, request, nil
+}
+
+// handleFetch answers a request with data fetched from the API, keeping a
+// copy in the local store shared with the CLI
+func handleFetch(fetch func(c *client.Client) (interface{}, error)) commandHandler {
+	return func(data json.RawMessage) (interface{}, error) {
+		c, _, err := clientFor(data)
+		if err != nil {
+			return nil, err
+		}
+		value, err := fetch(c)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Remember(c.SlackID, value); err != nil {
+			logf("Failed to update the local store: %v\n", err)
+		}
+		return value, nil
+	}
+}
+
+func handleStartCommand(data json.RawMessage) (interface{}, error) {
+	var request startData
+	if err := decodeData(data, &request); err != nil {
+		return nil, err
+	}
+	if request.Work == "" {
+		return nil, ipc.Errorf(ipc.CodeBadRequest, "invalid or missing 'work' value")
+	}
+	c, err := newClient(request.credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay actions queued by the CLI while offline before starting a new session
+	replayQueue(c, request.Profile)
+
+	// Perform the API POST request to start a new session
+	action, err := postToAPI(c, request.Work)
+
+	started := ipc.Event{SlackID: c.SlackID}
+	if err == nil {
+		started.SessionID = action.ID
+		publish(ipc.Event{Type: ipc.EventSessionStarted, SlackID: c.SlackID, SessionID: action.ID, Work: request.Work})
+	}
+
+	// Send a push notification based on the response
+	handleNotification(started, request.Work, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notifications of the new session begin right away
+	if _, err := trackSession(c, request.Profile); err != nil {
+		logf("Failed to track the new session: %v\n", err)
+	}
+	return action, nil
+}
+
+func handlePauseCommand(data json.RawMessage) (interface{}, error) {
+	return setPaused(data, true)
+}
+
+func handleResumeCommand(data json.RawMessage) (interface{}, error) {
+	return setPaused(data, false)
+}
+
+// setPaused pauses or resumes the current session. The API only toggles, so
+// the session is checked first and a request that would do the opposite
+// fails with a conflict.
+func setPaused(data json.RawMessage, paused bool) (interface{}, error) {
+	c, request, err := clientFor(data)
+	if err != nil {
+		return nil, err
+	}
+	session, err := c.Session()
+	if err != nil {
+		return nil, err
+	}
+	if session.Completed {
+		return nil, fmt.Errorf("%w: there is no active session", client.ErrConflict)
+	}
+	if session.Paused && paused {
+		return nil, fmt.Errorf("%w: the session is already paused", client.ErrConflict)
+	}
+	if !session.Paused && !paused {
+		return nil, fmt.Errorf("%w: the session is not paused", client.ErrConflict)
+	}
+
+	action, err := c.Pause()
+	if err != nil {
+		return nil, err
+	}
+
+	// Paused sessions do not run out, so their notifications wait for the resume
+	if paused {
+		stopTracker(c.SlackID)
+		publish(ipc.Event{Type: ipc.EventSessionPaused, SlackID: c.SlackID, SessionID: session.ID})
+		sendNotification(ipc.Event{SlackID: c.SlackID, SessionID: session.ID}, "Arcade Time Tracker", "Session paused")
+	} else {
+		publish(ipc.Event{Type: ipc.EventSessionResumed, SlackID: c.SlackID, SessionID: session.ID})
+		if _, err := trackSession(c, request.Profile); err != nil {
+			logf("Failed to track the resumed session: %v\n", err)
+		}
+		sendNotification(ipc.Event{SlackID: c.SlackID, SessionID: session.ID}, "Arcade Time Tracker", "Session resumed")
+	}
+	return action, nil
+}
+
+func handleCancelCommand(data json.RawMessage) (interface{}, error) {
+	c, _, err := clientFor(data)
+	if err != nil {
+		return nil, err
+	}
+	action, err := c.Cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	stopTracker(c.SlackID)
+	publish(ipc.Event{Type: ipc.EventSessionCancelled, SlackID: c.SlackID, SessionID: action.ID})
+	sendNotification(ipc.Event{SlackID: c.SlackID, SessionID: action.ID}, "Arcade Time Tracker", "Session cancelled")
+	return action, nil
+}
+
+func handleTrackCommand(data json.RawMessage) (interface{}, error) {
+	c, request, err := clientFor(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the tracking system with notifications
+	t, err := trackSession(c, request.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh the local store shared with the CLI
+	go syncStore(c)
+
+	return t, nil
+}
+
+func handleFlushCommand(data json.RawMessage) (interface{}, error) {
+	var request credentials
+	if err := decodeData(data, &request); err != nil {
+		return nil, err
+	}
+	c, err := newClient(request)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := replayQueue(c, request.Profile)
+	replayed := 0
+	for _, result := range results {
+		if result.Status == queue.StatusDone {
+			replayed++
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replayed %d queued action(s), stopped: %w", replayed, err)
+	}
+	if results == nil {
+		results = []queue.Result{}
+	}
+	return flushResult{Replayed: replayed, Results: results}, nil
+}
+
+func handleStatusCommand(data json.RawMessage) (interface{}, error) {
+	var request credentials
+	if err := decodeData(data, &request); err != nil {
+		return nil, err
+	}
+	c, err := newClient(request)
+	if err != nil {
+		return nil, err
+	}
+	return c.Status()
+}
+
+func handleHistoryCommand(data json.RawMessage) (interface{}, error) {
+	var request historyData
+	if err := decodeData(data, &request); err != nil {
+		return nil, err
+	}
+
+	filter := client.HistoryFilter{Match: request.Match, Goal: request.Goal, Sort: request.Sort, Limit: request.Limit, Page: request.Page}
+	var err error
+	now := time.Now()
+	if filter.Since, err = utils.ParseTimeSpec(request.Since, now); err != nil {
+		return nil, ipc.Errorf(ipc.CodeBadRequest, "since: %v", err)
+	}
+	if filter.Until, err = utils.ParseTimeSpec(request.Until, now); err != nil {
+		return nil, ipc.Errorf(ipc.CodeBadRequest, "until: %v", err)
+	}
+	if err := client.ValidateSort(filter.Sort); err != nil {
+		return nil, ipc.Errorf(ipc.CodeBadRequest, "%v", err)
+	}
+	if filter.Limit < 0 || filter.Page < 0 || (filter.Page > 0 && filter.Limit == 0) {
+		return nil, ipc.Errorf(ipc.CodeBadRequest, "limit and page must not be negative, and page requires limit")
+	}
+
+	c, err := newClient(request.credentials)
+	if err != nil {
+		return nil, err
+	}
+	replayQueue(c, request.Profile)
+	history, err := c.History()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Remember(c.SlackID, history); err != nil {
+		logf("Failed to update the local store: %v\n", err)
+	}
+	return history.Filter(filter), nil
+}
+
+func handleDaemonCommand(data json.RawMessage) (interface{}, error) {
+	return daemonResult{PID: os.Getpid(), StartedAt: startedAt, Socket: pipePath, HTTP: httpAddr, Trackers: activeTrackers()}, nil
+}
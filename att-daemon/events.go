@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"att/ipc"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped
+const subscriberBuffer = 64
+
+// events numbers the events and hands them to the subscribers
+var events = struct {
+	sync.Mutex
+	seq         uint64
+	subscribers map[chan ipc.Event]struct{}
+}{subscribers: make(map[chan ipc.Event]struct{})}
+
+// publish stamps an event with the time and the next sequence number and
+// sends it to every subscriber. Subscribers that can not keep up are
+// dropped, which closes their channel.
+func publish(e ipc.Event) {
+	events.Lock()
+	defer events.Unlock()
+	events.seq++
+	e.Seq = events.seq
+	e.Time = time.Now()
+	for ch := range events.subscribers {
+		select {
+		case ch <- e:
+		default:
+			logf("Dropping a subscriber that fell %d events behind\n", subscriberBuffer)
+			delete(events.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns a channel receiving the events published from now on,
+// and the sequence number of the last event before them
+func subscribe() (chan ipc.Event, uint64) {
+	events.Lock()
+	defer events.Unlock()
+	ch := make(chan ipc.Event, subscriberBuffer)
+	events.subscribers[ch] = struct{}{}
+	return ch, events.seq
+}
+
+// unsubscribe stops sending events to a channel
+func unsubscribe(ch chan ipc.Event) {
+	events.Lock()
+	defer events.Unlock()
+	if _, ok := events.subscribers[ch]; ok {
+		delete(events.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publishAPIError publishes a failed request of a user to the API
+func publishAPIError(slackID, command string, err error) {
+	e := ipc.ErrorFrom(err)
+	publish(ipc.Event{Type: ipc.EventAPIError, SlackID: slackID, Command: command, Code: e.Code, Message: redactSecrets(e.Message)})
+}
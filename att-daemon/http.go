@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"att/ipc"
+	"att/utils"
+)
+
+// codeInvalidToken is the error code of HTTP requests without the bearer
+// token of the install
+const codeInvalidToken = "invalid_token"
+
+// keepAliveInterval is the time between two comments on an idle event stream
+const keepAliveInterval = 30 * time.Second
+
+// httpMethods tells the HTTP method of each command of the HTTP API, which
+// mirrors the socket commands below /v1/
+var httpMethods = map[string]string{
+	"start":   http.MethodPost,
+	"pause":   http.MethodPost,
+	"resume":  http.MethodPost,
+	"cancel":  http.MethodPost,
+	"track":   http.MethodPost,
+	"flush":   http.MethodPost,
+	"session": http.MethodGet,
+	"stats":   http.MethodGet,
+	"goals":   http.MethodGet,
+	"history": http.MethodGet,
+	"status":  http.MethodGet,
+	"daemon":  http.MethodGet,
+}
+
+// httpStatuses maps error codes to HTTP status codes. The API rejecting the
+// credentials is 403, as 401 means the bearer token is wrong.
+var httpStatuses = map[string]int{
+	ipc.CodeBadRequest:        http.StatusBadRequest,
+	ipc.CodeUnknownCommand:    http.StatusNotFound,
+	ipc.CodeNotConfigured:     http.StatusServiceUnavailable,
+	ipc.CodeUnauthorized:      http.StatusForbidden,
+	ipc.CodeConflict:          http.StatusConflict,
+	ipc.CodeBaseURLMismatch:   http.StatusConflict,
+	ipc.CodeSlackIDMismatch:   http.StatusConflict,
+	ipc.CodeNetwork:           http.StatusBadGateway,
+	ipc.CodeServer:            http.StatusBadGateway,
+	ipc.CodeMalformedResponse: http.StatusBadGateway,
+	codeInvalidToken:          http.StatusUnauthorized,
+}
+
+// serveHTTP answers the HTTP API on a listener until it is closed
+func serveHTTP(listener net.Listener) error {
+	server := &http.Server{Handler: httpHandler(), ReadHeaderTimeout: 10 * time.Second}
+	return server.Serve(listener)
+}
+
+// httpHandler routes the requests of the HTTP API
+func httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/events", authorized(handleHTTPEvents))
+	mux.HandleFunc("/v1/", authorized(handleHTTPCommand))
+	return mux
+}
+
+// authorized rejects requests without the bearer token of the install in
+// the Authorization header. The token is created when attd starts and read
+// on every request, so a rotated token applies right away. Tokens in query
+// strings are not accepted, as they end up in logs and browser history.
+func authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := utils.ReadHTTPToken()
+		if err != nil {
+			logf("Failed to load the HTTP API token: %v\n", err)
+			writeHTTPError(w, ipc.Errorf(ipc.CodeError, "unable to load the HTTP API token"))
+			return
+		}
+
+		given := ""
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			given = strings.TrimPrefix(header, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="attd"`)
+			writeHTTPError(w, ipc.Errorf(codeInvalidToken, "missing or invalid bearer token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleHTTPCommand runs the command named by the path. POST requests take
+// the request data as a JSON body, GET requests as query parameters.
+func handleHTTPCommand(w http.ResponseWriter, r *http.Request) {
+	command := strings.TrimPrefix(r.URL.Path, "/v1/")
+	method, ok := httpMethods[command]
+	if !ok {
+		writeHTTPError(w, ipc.Errorf(ipc.CodeUnknownCommand, "unknown command %q", command))
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeHTTPJSON(w, http.StatusMethodNotAllowed, errorBody(ipc.Errorf(ipc.CodeBadRequest, "%s only accepts %s", r.URL.Path, method)))
+		return
+	}
+
+	var data json.RawMessage
+	var err error
+	if method == http.MethodPost {
+		data, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, ipc.MaxMessageSize))
+		if err != nil {
+			writeHTTPError(w, ipc.Errorf(ipc.CodeBadRequest, "unable to read request: %v", err))
+			return
+		}
+	} else if data, err = queryData(r.URL.Query()); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	logf("Received %s HTTP request\n", command)
+	result, err := execute(command, commands[command], data)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, result)
+}
+
+// queryData converts query parameters into request data. limit and page
+// are numbers, every other parameter is a string. API keys are refused, as
+// query strings end up in logs; they can only be sent in a POST body.
+func queryData(query url.Values) (json.RawMessage, error) {
+	if len(query) == 0 {
+		return nil, nil
+	}
+	data := make(map[string]interface{}, len(query))
+	for key := range query {
+		value := query.Get(key)
+		switch key {
+		case "api_key":
+			return nil, ipc.Errorf(ipc.CodeBadRequest, "api_key is not accepted in the query string")
+		case "limit", "page":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, ipc.Errorf(ipc.CodeBadRequest, "%s: %q is not a number", key, value)
+			}
+			data[key] = n
+		default:
+			data[key] = value
+		}
+	}
+	raw, err := json.Marshal(data)
+	return raw, err
+}
+
+// handleHTTPEvents streams the events of attd as Server-Sent Events until
+// the client goes away
+func handleHTTPEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeHTTPJSON(w, http.StatusMethodNotAllowed, errorBody(ipc.Errorf(ipc.CodeBadRequest, "%s only accepts GET", r.URL.Path)))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, ipc.Errorf(ipc.CodeError, "streaming is not supported"))
+		return
+	}
+
+	ch, seq := subscribe()
+	defer unsubscribe(ch)
+	logf("Streaming events over HTTP after event %d\n", seq)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": subscribed after event %d\n\n", seq)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				// The client read too slowly and was dropped
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// errorBody is the body of a failed HTTP request
+func errorBody(e *ipc.Error) interface{} {
+	return struct {
+		Error *ipc.Error `json:"error"`
+	}{e}
+}
+
+// writeHTTPError answers an HTTP request with an error and the status
+// matching its code
+func writeHTTPError(w http.ResponseWriter, err error) {
+	e := ipc.ErrorFrom(err)
+	status, ok := httpStatuses[e.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeHTTPJSON(w, status, errorBody(e))
+}
+
+// writeHTTPJSON answers an HTTP request with a JSON body
+func writeHTTPJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logf("Failed to write the HTTP response: %v\n", err)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"att/ipc"
+	"att/utils"
+)
+
+// serveTestHTTP starts the HTTP API of attd on a test server and returns
+// its URL and bearer token
+func serveTestHTTP(t *testing.T) (string, string) {
+	t.Helper()
+	setupMock(t, time.Hour)
+	token, err := utils.HTTPToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(httpHandler())
+	t.Cleanup(server.Close)
+	return server.URL, token
+}
+
+// httpErrorCode returns the error code of an HTTP API response body
+func httpErrorCode(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	var body struct {
+		Error *ipc.Error `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding the error body: %v", err)
+	}
+	if body.Error == nil {
+		return ""
+	}
+	return body.Error.Code
+}
+
+func TestHTTPAuthorization(t *testing.T) {
+	url, token := serveTestHTTP(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+		code   string
+	}{
+		{"bearer token", "/v1/daemon", "Bearer " + token, http.StatusOK, ""},
+		{"no header", "/v1/daemon", "", http.StatusUnauthorized, codeInvalidToken},
+		{"wrong token", "/v1/daemon", "Bearer wrong", http.StatusUnauthorized, codeInvalidToken},
+		{"other scheme", "/v1/daemon", "Basic " + token, http.StatusUnauthorized, codeInvalidToken},
+		{"bare token", "/v1/daemon", token, http.StatusUnauthorized, codeInvalidToken},
+		{"token in query", "/v1/daemon?token=" + token, "", http.StatusUnauthorized, codeInvalidToken},
+		{"access_token in query", "/v1/daemon?access_token=" + token, "", http.StatusUnauthorized, codeInvalidToken},
+		{"events without token", "/v1/events", "", http.StatusUnauthorized, codeInvalidToken},
+		{"api_key in query", "/v1/status?api_key=secret", "Bearer " + token, http.StatusBadRequest, ipc.CodeBadRequest},
+		{"bad limit", "/v1/history?limit=ten", "Bearer " + token, http.StatusBadRequest, ipc.CodeBadRequest},
+		{"unknown command", "/v1/nope", "Bearer " + token, http.StatusNotFound, ipc.CodeUnknownCommand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, url+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is missing")
+			}
+			if tt.code == "" {
+				return
+			}
+			if code := httpErrorCode(t, resp); code != tt.code {
+				t.Errorf("code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestHTTPMethods(t *testing.T) {
+	url, token := serveTestHTTP(t)
+
+	tests := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodPost, "/v1/daemon", http.MethodGet},
+		{http.MethodGet, "/v1/start", http.MethodPost},
+		{http.MethodPost, "/v1/events", http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, url+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+			}
+			if allow := resp.Header.Get("Allow"); allow != tt.allow {
+				t.Errorf("Allow = %q, want %q", allow, tt.allow)
+			}
+		})
+	}
+}
+
+func TestHTTPRotatedToken(t *testing.T) {
+	url, token := serveTestHTTP(t)
+	rotated, err := utils.RotateHTTPToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated == token {
+		t.Fatal("the rotated token is the old one")
+	}
+
+	for given, want := range map[string]int{token: http.StatusUnauthorized, rotated: http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, url+"/v1/daemon", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+given)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("status = %d, want %d", resp.StatusCode, want)
+		}
+	}
+}
+
+func TestHTTPEvents(t *testing.T) {
+	url, token := serveTestHTTP(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/v1/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", contentType)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() []string {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading the stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if lines := readEvent(); len(lines) != 1 || !strings.HasPrefix(lines[0], ": subscribed after event ") {
+		t.Fatalf("first event = %q, want the subscription comment", lines)
+	}
+
+	publish(ipc.Event{Type: ipc.EventSessionStarted, SlackID: "U1", SessionID: "s1"})
+	lines := readEvent()
+	if len(lines) != 3 {
+		t.Fatalf("event = %q, want id, event and data lines", lines)
+	}
+	if !strings.HasPrefix(lines[0], "id: ") {
+		t.Errorf("line 1 = %q, want an id", lines[0])
+	}
+	if lines[1] != "event: "+ipc.EventSessionStarted {
+		t.Errorf("line 2 = %q, want event: %s", lines[1], ipc.EventSessionStarted)
+	}
+	var e ipc.Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &e); err != nil {
+		t.Fatalf("line 3 = %q: %v", lines[2], err)
+	}
+	if e.Type != ipc.EventSessionStarted || e.SlackID != "U1" || e.SessionID != "s1" {
+		t.Errorf("data = %+v, want the published event", e)
+	}
+	if lines[0] != "id: "+strconv.FormatUint(e.Seq, 10) {
+		t.Errorf("id line = %q, want the event seq %d", lines[0], e.Seq)
+	}
+}
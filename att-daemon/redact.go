@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// minSecretLength is the shortest value hidden by redactSecrets, shorter
+// ones would mangle unrelated output
+const minSecretLength = 6
+
+// secrets holds the API tokens attd has used, which never appear in its
+// output
+var secrets = struct {
+	sync.Mutex
+	values map[string]bool
+}{values: make(map[string]bool)}
+
+// addSecret registers a value to hide from the daemon output
+func addSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values[value] = true
+}
+
+// redactSecrets replaces the registered secrets in s
+func redactSecrets(s string) string {
+	secrets.Lock()
+	defer secrets.Unlock()
+	for value := range secrets.values {
+		s = strings.ReplaceAll(s, value, "[redacted]")
+	}
+	return s
+}
+
+// logf prints a message with the secrets redacted
+func logf(format string, args ...interface{}) {
+	fmt.Print(redactSecrets(fmt.Sprintf(format, args...)))
+}
+
+// logln prints its operands and a newline with the secrets redacted
+func logln(args ...interface{}) {
+	fmt.Print(redactSecrets(fmt.Sprintln(args...)))
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"att/client"
+	"att/ipc"
+	"att/utils"
+)
+
+// tracker sends the notifications of the session of a user. Trackers are
+// saved in the state directory and restored when attd starts.
+type tracker struct {
+	Profile   string    `json:"profile,omitempty"`
+	SlackID   string    `json:"slackId"`
+	SessionID string    `json:"sessionId"`
+	CreatedAt time.Time `json:"createdAt"`
+	EndTime   time.Time `json:"endTime"`
+
+	stop chan struct{}
+	// settings are the notification settings when the tracker started
+	settings utils.NotificationSettings
+}
+
+// trackers holds the running trackers by Slack ID
+var trackers = struct {
+	sync.Mutex
+	bySlackID map[string]*tracker
+}{bySlackID: make(map[string]*tracker)}
+
+// trackerRoutines counts the running tracker goroutines, including the ones
+// of stopped trackers that have not returned yet
+var trackerRoutines sync.WaitGroup
+
+// restoreRetry is the time between two checks of a restored session while
+// the API is unreachable
+const restoreRetry = time.Minute
+
+// completeRetry is the time between two checks of a session whose end time
+// passed while the API does not report it completed, and completeChecks the
+// number of checks before the local timer is trusted
+const (
+	completeRetry  = 30 * time.Second
+	completeChecks = 5
+)
+
+// trackSession fetches the current session of the client's user and starts
+// sending its notifications, replacing an earlier tracker of the user.
+// profile is the profile of the request, which is saved with the tracker.
+func trackSession(c *client.Client, profile string) (*tracker, error) {
+	session, err := c.Session()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch session: %w", err)
+	}
+	if session.EndTime.IsZero() || session.CreatedAt.IsZero() {
+		return nil, fmt.Errorf("session has no createdAt or endTime")
+	}
+	if session.Completed {
+		return nil, fmt.Errorf("%w: the session has ended", client.ErrConflict)
+	}
+	if session.Paused {
+		return nil, fmt.Errorf("%w: the session is paused", client.ErrConflict)
+	}
+
+	t := &tracker{
+		Profile:   profile,
+		SlackID:   c.SlackID,
+		SessionID: session.ID,
+		CreatedAt: session.CreatedAt,
+		EndTime:   session.EndTime,
+	}
+	t.start(nil)
+	return t, nil
+}
+
+// start registers the tracker, replacing an earlier one of the user, and
+// sends its notifications until the session ends or the tracker is
+// stopped. When check is set it runs first, and the tracker is dropped
+// when it fails.
+func (t *tracker) start(check func() bool) {
+	t.stop = make(chan struct{})
+	t.settings = notifications
+	trackers.Lock()
+	if old := trackers.bySlackID[t.SlackID]; old != nil {
+		close(old.stop)
+	}
+	trackers.bySlackID[t.SlackID] = t
+	saveTrackers()
+	trackers.Unlock()
+
+	logf("Tracking session %s until %s\n", t.SessionID, t.EndTime)
+	trackerRoutines.Add(1)
+	go func() {
+		defer trackerRoutines.Done()
+		if check == nil || check() {
+			setupNotificationsFrom(t, time.Now())
+		}
+
+		trackers.Lock()
+		if trackers.bySlackID[t.SlackID] == t {
+			delete(trackers.bySlackID, t.SlackID)
+			saveTrackers()
+		}
+		trackers.Unlock()
+	}()
+}
+
+// stopTracker stops the notifications of a user's session
+func stopTracker(slackID string) {
+	trackers.Lock()
+	defer trackers.Unlock()
+	if t := trackers.bySlackID[slackID]; t != nil {
+		close(t.stop)
+		delete(trackers.bySlackID, slackID)
+		saveTrackers()
+		logf("Stopped tracking session %s\n", t.SessionID)
+	}
+}
+
+// trackersPath returns the path of the file the trackers are saved in
+func trackersPath() (string, error) {
+	dir, err := utils.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trackers.json"), nil
+}
+
+// saveTrackers writes the running trackers to the state directory. The
+// caller holds the trackers lock.
+func saveTrackers() {
+	saved := make([]*tracker, 0, len(trackers.bySlackID))
+	for _, t := range trackers.bySlackID {
+		saved = append(saved, t)
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].SlackID < saved[j].SlackID
+	})
+
+	path, err := trackersPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err == nil {
+		var data []byte
+		data, err = json.MarshalIndent(saved, "", "  ")
+		if err == nil {
+			err = utils.WriteFileAtomic(path, append(data, '\n'), 0600)
+		}
+	}
+	if err != nil {
+		logf("Failed to save the trackers: %v\n", err)
+	}
+}
+
+// restoreTrackers re-arms the trackers saved by an earlier run of attd.
+// Each session is checked with the API first, and sessions that ended
+// meanwhile are dropped.
+func restoreTrackers() {
+	path, err := trackersPath()
+	if err != nil {
+		logf("Failed to restore the trackers: %v\n", err)
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	var saved []*tracker
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		logf("Failed to restore the trackers from %s: %v\n", path, err)
+		return
+	}
+
+	for _, t := range saved {
+		if !t.EndTime.After(time.Now()) {
+			logf("Dropping the tracker of session %s, it ended at %s\n", t.SessionID, t.EndTime)
+			continue
+		}
+		logf("Restoring the tracker of session %s\n", t.SessionID)
+		t.start(t.checkActive)
+	}
+
+	// Drop the expired trackers from the file as well
+	trackers.Lock()
+	saveTrackers()
+	trackers.Unlock()
+}
+
+// checkActive reports whether the session of a restored tracker is still
+// the active session of its user, retrying while the API is unreachable
+func (t *tracker) checkActive() bool {
+	for {
+		err := t.fetchActive()
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, client.ErrNetwork) && !errors.Is(err, client.ErrServer) {
+			logf("Dropping the tracker of session %s: %v\n", t.SessionID, err)
+			return false
+		}
+		if time.Until(t.EndTime) < restoreRetry {
+			logf("Dropping the tracker of session %s, the API was unreachable until it ended: %v\n", t.SessionID, err)
+			return false
+		}
+		logf("Unable to check session %s, retrying in %s: %v\n", t.SessionID, restoreRetry, err)
+		publishAPIError(t.SlackID, "session", err)
+		if !sleep(t.stop, restoreRetry) {
+			return false
+		}
+	}
+}
+
+// apiClient creates an API client for the user of the tracker
+func (t *tracker) apiClient() (*client.Client, error) {
+	return newClient(credentials{Profile: t.Profile, SlackID: t.SlackID})
+}
+
+// fetchActive fetches the session of a restored tracker, failing when it
+// is no longer running
+func (t *tracker) fetchActive() error {
+	c, err := t.apiClient()
+	if err != nil {
+		return err
+	}
+	replayQueue(c, t.Profile)
+	session, err := c.Session()
+	switch {
+	case err != nil:
+		return err
+	case session.ID != t.SessionID:
+		return fmt.Errorf("it is no longer the current session")
+	case session.Completed:
+		return fmt.Errorf("the session has ended")
+	case session.Paused:
+		return fmt.Errorf("the session is paused")
+	}
+	return nil
+}
+
+// activeTrackers returns the running trackers ordered by end time
+func activeTrackers() []*tracker {
+	trackers.Lock()
+	defer trackers.Unlock()
+	active := make([]*tracker, 0, len(trackers.bySlackID))
+	for _, t := range trackers.bySlackID {
+		active = append(active, t)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].EndTime.Before(active[j].EndTime)
+	})
+	return active
+}
+
+// sleep waits for d and reports false when stop is closed first
+func sleep(stop <-chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// setupNotificationsFrom sends the reminders of a tracked session until it
+// ends or the tracker is stopped
+func setupNotificationsFrom(t *tracker, createdAt time.Time) {
+	if t.settings.Disabled {
+		if sleep(t.stop, time.Until(t.EndTime)) {
+			t.complete()
+		}
+		return
+	}
+	if schedule, _ := t.settings.ScheduleDurations(); len(schedule) > 0 {
+		setupScheduledNotifications(t, schedule)
+		return
+	}
+
+	currentTime := createdAt
+
+	for currentTime.Before(t.EndTime) {
+		timeRemain := int(t.EndTime.Sub(currentTime).Minutes())
+		if timeRemain <= 0 {
+			break
+		}
+
+		switch {
+		case timeRemain > 10 && timeRemain%20 == 0:
+			t.notify(fmt.Sprintf("You have %d minutes left!", timeRemain))
+			if !sleep(t.stop, 20*time.Minute) {
+				return
+			}
+		case timeRemain == 10:
+			t.notify("Just 10 minutes left!")
+			if !sleep(t.stop, 10*time.Minute) {
+				return
+			}
+		case timeRemain == 5:
+			t.notify("The last 5 minutes!")
+			if !sleep(t.stop, 5*time.Minute) {
+				return
+			}
+		default:
+			if !sleep(t.stop, 1*time.Minute) {
+				return
+			}
+		}
+
+		currentTime = time.Now()
+	}
+
+	t.complete()
+}
+
+// setupScheduledNotifications sends a reminder when each remaining time of
+// the configured schedule is reached, skipping the ones already passed
+func setupScheduledNotifications(t *tracker, schedule []time.Duration) {
+	for _, remaining := range schedule {
+		wait := time.Until(t.EndTime.Add(-remaining))
+		if wait < 0 {
+			continue
+		}
+		if !sleep(t.stop, wait) {
+			return
+		}
+		left := remaining.String()
+		if remaining%time.Minute == 0 {
+			left = fmt.Sprintf("%d minutes", int(remaining.Minutes()))
+		}
+		t.notify(fmt.Sprintf("You have %s left!", left))
+	}
+
+	if !sleep(t.stop, time.Until(t.EndTime)) {
+		return
+	}
+	t.complete()
+}
+
+// notify sends a notification about the tracked session
+func (t *tracker) notify(message string) {
+	sendNotification(ipc.Event{SlackID: t.SlackID, SessionID: t.SessionID}, "Arcade Time Tracker", message)
+}
+
+// complete announces the end of the tracked session once the API reports it
+// completed. A session paused, cancelled or replaced meanwhile is dropped,
+// and one extended by a pause is tracked again until its new end time. When
+// the API can not confirm the end, the local timer is trusted.
+func (t *tracker) complete() {
+	for checks := 1; ; checks++ {
+		var session *client.Session
+		c, err := t.apiClient()
+		if err == nil {
+			session, err = c.Session()
+		}
+
+		switch {
+		case err != nil && checks < completeChecks && (errors.Is(err, client.ErrNetwork) || errors.Is(err, client.ErrServer)):
+			logf("Unable to check the end of session %s, retrying in %s: %v\n", t.SessionID, completeRetry, err)
+		case err != nil:
+			logf("Unable to check the end of session %s, trusting the local timer: %v\n", t.SessionID, err)
+			t.announceCompleted()
+			return
+		case session.ID != t.SessionID:
+			logf("Dropping the tracker of session %s, it is no longer the current session\n", t.SessionID)
+			return
+		case session.Completed && session.Elapsed < session.Time:
+			logf("Dropping the tracker of session %s, it was cancelled\n", t.SessionID)
+			return
+		case session.Completed:
+			t.announceCompleted()
+			return
+		case session.Paused:
+			logf("Dropping the tracker of session %s, it is paused\n", t.SessionID)
+			return
+		case session.EndTime.After(t.EndTime):
+			logf("Session %s was extended until %s\n", t.SessionID, session.EndTime)
+			extended := &tracker{Profile: t.Profile, SlackID: t.SlackID, SessionID: t.SessionID, CreatedAt: t.CreatedAt, EndTime: session.EndTime}
+			extended.start(nil)
+			return
+		case checks >= completeChecks:
+			logf("The API still reports session %s running, trusting the local timer\n", t.SessionID)
+			t.announceCompleted()
+			return
+		}
+
+		if !sleep(t.stop, completeRetry) {
+			return
+		}
+	}
+}
+
+// announceCompleted publishes the end of the tracked session and sends its
+// notification
+func (t *tracker) announceCompleted() {
+	publish(ipc.Event{Type: ipc.EventSessionCompleted, SlackID: t.SlackID, SessionID: t.SessionID})
+	if !t.settings.Disabled {
+		t.notify("You did it!")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"att/client"
+	"att/ipc"
+	"att/mockserver"
+	"att/testenv"
+	"att/utils"
+)
+
+// setupMock points attd at a mock server with private att directories and
+// a default profile for U1, and returns a client of the mock. Notifications
+// are turned off. The trackers started by the test are stopped and joined
+// before the settings are put back.
+func setupMock(t *testing.T, sessionLength time.Duration) *client.Client {
+	t.Helper()
+	mock := mockserver.New(testenv.Token)
+	mock.SessionLength = sessionLength
+	server := testenv.Serve(t, mock)
+
+	oldNotifications, oldBaseURL := notifications, baseURLFlag
+	t.Cleanup(func() {
+		notifications, baseURLFlag = oldNotifications, oldBaseURL
+	})
+	t.Cleanup(stopTrackers)
+	notifications = utils.NotificationSettings{Disabled: true}
+	baseURLFlag = server.URL
+
+	err := utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		config.Profiles[utils.DefaultProfile] = map[string]string{"api-token": testenv.Token, "slack-id": "U1"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.New(server.URL, testenv.Token, "U1")
+}
+
+// stopTrackers stops every tracker and waits until their goroutines return
+func stopTrackers() {
+	for _, t := range activeTrackers() {
+		stopTracker(t.SlackID)
+	}
+	trackerRoutines.Wait()
+}
+
+// savedTrackers reads the trackers saved in the state directory
+func savedTrackers(t *testing.T) []*tracker {
+	t.Helper()
+	path, err := trackersPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []*tracker
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+func TestTrackerCompletes(t *testing.T) {
+	c := setupMock(t, 300*time.Millisecond)
+	events, _ := subscribe()
+	defer unsubscribe(events)
+
+	if _, err := c.Start("make robot"); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := trackSession(c, "")
+	if err != nil {
+		t.Fatalf("trackSession: %v", err)
+	}
+	if saved := savedTrackers(t); len(saved) != 1 || saved[0].SessionID != tr.SessionID {
+		t.Errorf("saved trackers = %+v, want session %s", saved, tr.SessionID)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != ipc.EventSessionCompleted {
+				continue
+			}
+			if e.SessionID != tr.SessionID || e.SlackID != "U1" {
+				t.Errorf("completed event = %+v", e)
+			}
+			// The tracker removes itself once the session is over
+			for deadline := time.Now().Add(time.Second); len(activeTrackers()) > 0 && time.Now().Before(deadline); {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if active := activeTrackers(); len(active) != 0 {
+				t.Errorf("active trackers = %+v, want none", active)
+			}
+			return
+		case <-timeout:
+			t.Fatal("no session.completed event")
+		}
+	}
+}
+
+func TestTrackerDropsReplacedSession(t *testing.T) {
+	c := setupMock(t, 300*time.Millisecond)
+	events, _ := subscribe()
+	defer unsubscribe(events)
+
+	if _, err := c.Start("make robot"); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := trackSession(c, "")
+	if err != nil {
+		t.Fatalf("trackSession: %v", err)
+	}
+	// The session is cancelled and replaced without telling attd
+	if _, err := c.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Start("make another"); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == ipc.EventSessionCompleted {
+				t.Fatalf("completed event %+v for a replaced session", e)
+			}
+		case <-timeout:
+			if active := activeTrackers(); len(active) != 0 {
+				t.Errorf("active trackers = %+v, want the tracker of %s dropped", active, tr.SessionID)
+			}
+			return
+		}
+	}
+}
+
+func TestTrackerRejectsPausedSession(t *testing.T) {
+	c := setupMock(t, time.Hour)
+	if _, err := c.Start("make robot"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trackSession(c, ""); err == nil {
+		t.Fatal("trackSession of a paused session succeeded")
+	}
+}
+
+func TestStopTracker(t *testing.T) {
+	c := setupMock(t, time.Hour)
+	if _, err := c.Start("make robot"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trackSession(c, ""); err != nil {
+		t.Fatalf("trackSession: %v", err)
+	}
+	stopTracker("U1")
+	if active := activeTrackers(); len(active) != 0 {
+		t.Errorf("active trackers = %+v, want none", active)
+	}
+	if saved := savedTrackers(t); len(saved) != 0 {
+		t.Errorf("saved trackers = %+v, want none", saved)
+	}
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"att/utils"
+)
+
+// Client talks to the Hack Hour API on behalf of a single user
+type Client struct {
+	BaseURL  string
+	APIToken string
+	SlackID  string
+}
+
+// New creates a new API client
+func New(baseURL, apiToken, slackID string) *Client {
+	return &Client{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		APIToken: apiToken,
+		SlackID:  slackID,
+	}
+}
+
+// envelope is the common wrapper of all /api responses
+type envelope struct {
+	OK    bool            `json:"ok"`
+	Data  json.RawMessage `json:"data"`
+	Error string          `json:"error"`
+}
+
+// Ping pings the server and returns the raw answer
+func (c *Client) Ping() (string, error) {
+	body, err := c.do("GET", "/ping", nil, false)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// Status fetches the status of hack hour
+func (c *Client) Status() (*Status, error) {
+	body, err := c.do("GET", "/status", nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var status Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, &MalformedResponseError{Err: err}
+	}
+	return &status, nil
+}
+
+// Session fetches the latest session of the user
+func (c *Client) Session() (*Session, error) {
+	var session Session
+	if err := c.api("GET", "session", nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Stats fetches the stats of the user
+func (c *Client) Stats() (*Stats, error) {
+	var stats Stats
+	if err := c.api("GET", "stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Goals fetches the goals of the user
+func (c *Client) Goals() (Goals, error) {
+	var goals Goals
+	if err := c.api("GET", "goals", nil, &goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// History fetches the session history of the user
+func (c *Client) History() (History, error) {
+	var history History
+	if err := c.api("GET", "history", nil, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Start starts a new session
+func (c *Client) Start(work string) (*SessionAction, error) {
+	payload, err := json.Marshal(map[string]string{"work": work})
+	if err != nil {
+		return nil, err
+	}
+
+	var action SessionAction
+	if err := c.api("POST", "start", payload, &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// Pause pauses or resumes the current session
+func (c *Client) Pause() (*SessionAction, error) {
+	var action SessionAction
+	if err := c.api("POST", "pause", nil, &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// Cancel cancels the current session
+func (c *Client) Cancel() (*SessionAction, error) {
+	var action SessionAction
+	if err := c.api("POST", "cancel", nil, &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// api calls an authenticated /api endpoint and decodes its data into out
+func (c *Client) api(method, endpoint string, payload []byte, out interface{}) error {
+	if c.APIToken == "" || c.SlackID == "" {
+		return ErrNotConfigured
+	}
+
+	body, err := c.do(method, fmt.Sprintf("/api/%s/%s", endpoint, c.SlackID), payload, true)
+	var apiErr *APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		return err
+	}
+
+	var result envelope
+	if jsonErr := json.Unmarshal(body, &result); jsonErr != nil {
+		if apiErr != nil {
+			return apiErr
+		}
+		return &MalformedResponseError{Err: jsonErr}
+	}
+
+	if apiErr != nil || !result.OK {
+		if apiErr == nil {
+			apiErr = &APIError{StatusCode: http.StatusOK}
+		}
+		apiErr.Message = result.Error
+		return apiErr
+	}
+
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return &MalformedResponseError{Err: err}
+	}
+	return nil
+}
+
+// do performs a request and returns the response body. A non-200 status
+// is reported as an *APIError alongside the body.
+func (c *Client) do(method, path string, payload []byte, auth bool) ([]byte, error) {
+	apiToken := ""
+	if auth {
+		apiToken = c.APIToken
+	}
+
+	resp, err := utils.MakeAPIRequest(method, c.BaseURL+path, payload, apiToken)
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &NetworkError{Err: fmt.Errorf("unable to read response body: %w", err)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return body, &APIError{StatusCode: resp.StatusCode}
+	}
+	return body, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error categories, test for them with errors.Is
+var (
+	// ErrNotConfigured is returned when an authenticated endpoint is called
+	// without an API token or Slack ID
+	ErrNotConfigured = errors.New("API token and Slack ID are not set")
+	// ErrUnauthorized is returned when the server rejects the credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrConflict is returned when the action conflicts with the session
+	// state, e.g. starting a session while one is active
+	ErrConflict = errors.New("conflicting session state")
+	// ErrNetwork is returned when the server could not be reached
+	ErrNetwork = errors.New("network error")
+	// ErrServer is returned when the server failed to handle the request
+	ErrServer = errors.New("server error")
+	// ErrMalformedResponse is returned when the response can not be decoded
+	ErrMalformedResponse = errors.New("malformed response")
+)
+
+// APIError is returned when the server answers with a non-OK response
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("received status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("received status code %d with message: %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code and message to an error category
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrConflict:
+		message := strings.ToLower(e.Message)
+		return e.StatusCode == http.StatusConflict ||
+			strings.Contains(message, "active session")
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// MalformedResponseError is returned when the response body can not be decoded
+type MalformedResponseError struct {
+	Err error
+}
+
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("malformed response: %v", e.Err)
+}
+
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ErrMalformedResponse as the category of the error
+func (e *MalformedResponseError) Is(target error) bool {
+	return target == ErrMalformedResponse
+}
+
+// NetworkError is returned when the request could not be completed
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("unable to reach the server: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ErrNetwork as the category of the error
+func (e *NetworkError) Is(target error) bool {
+	return target == ErrNetwork
+}
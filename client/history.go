@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistorySortKeys lists the fields history can be sorted by. Prefix a key
+// with "-" to sort in descending order.
+var HistorySortKeys = []string{"createdAt", "time", "elapsed", "goal", "work"}
+
+// HistoryFilter narrows down and orders history entries
+type HistoryFilter struct {
+	// Since and Until bound the creation time, zero values are open ends
+	Since time.Time
+	Until time.Time
+	// Match is a case-insensitive substring of the work description
+	Match string
+	// Goal is the case-insensitive name of the goal
+	Goal string
+	// Sort is one of HistorySortKeys, optionally prefixed with "-"
+	Sort string
+	// Limit is the page size and Page the 1-based page, zero means no limit
+	Limit int
+	Page  int
+}
+
+// ValidateSort checks that key is a supported sort key
+func ValidateSort(key string) error {
+	if key == "" {
+		return nil
+	}
+	name := strings.TrimPrefix(key, "-")
+	for _, k := range HistorySortKeys {
+		if k == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown sort key %q (expected one of %s)", key, strings.Join(HistorySortKeys, ", "))
+}
+
+// Filter returns the entries matching f, sorted and paginated
+func (h History) Filter(f HistoryFilter) History {
+	match := strings.ToLower(f.Match)
+	result := History{}
+	for _, entry := range h {
+		if !f.Since.IsZero() && entry.CreatedAt.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && !entry.CreatedAt.Before(f.Until) {
+			continue
+		}
+		if match != "" && !strings.Contains(strings.ToLower(entry.Work), match) {
+			continue
+		}
+		if f.Goal != "" && !strings.EqualFold(entry.Goal, f.Goal) {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	if f.Sort != "" {
+		descending := strings.HasPrefix(f.Sort, "-")
+		less := historyLess(strings.TrimPrefix(f.Sort, "-"))
+		sort.SliceStable(result, func(i, j int) bool {
+			if descending {
+				return less(result[j], result[i])
+			}
+			return less(result[i], result[j])
+		})
+	}
+
+	if f.Limit > 0 {
+		page := f.Page
+		if page < 1 {
+			page = 1
+		}
+		start := (page - 1) * f.Limit
+		if start >= len(result) {
+			return History{}
+		}
+		end := start + f.Limit
+		if end > len(result) {
+			end = len(result)
+		}
+		result = result[start:end]
+	}
+	return result
+}
+
+// historyLess returns the ordering of a sort key
+func historyLess(key string) func(a, b HistoryEntry) bool {
+	switch key {
+	case "time":
+		return func(a, b HistoryEntry) bool { return a.Time < b.Time }
+	case "elapsed":
+		return func(a, b HistoryEntry) bool { return a.Elapsed < b.Elapsed }
+	case "goal":
+		return func(a, b HistoryEntry) bool { return strings.ToLower(a.Goal) < strings.ToLower(b.Goal) }
+	case "work":
+		return func(a, b HistoryEntry) bool { return strings.ToLower(a.Work) < strings.ToLower(b.Work) }
+	}
+	return func(a, b HistoryEntry) bool { return a.CreatedAt.Before(b.CreatedAt) }
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 7, d, 10, 0, 0, 0, time.UTC) }
+	history := History{
+		{CreatedAt: day(1), Time: 60, Elapsed: 60, Goal: "No Goal", Work: "Write the parser"},
+		{CreatedAt: day(2), Time: 30, Elapsed: 12, Goal: "att", Work: "Fix the daemon"},
+		{CreatedAt: day(3), Time: 45, Elapsed: 45, Goal: "ATT", Work: "Parser tests"},
+		{CreatedAt: day(4), Time: 60, Elapsed: 20, Goal: "Website", Work: "Landing page"},
+	}
+	works := func(h History) []string {
+		result := []string{}
+		for _, entry := range h {
+			result = append(result, entry.Work)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		want   []string
+	}{
+		{"everything", HistoryFilter{}, []string{"Write the parser", "Fix the daemon", "Parser tests", "Landing page"}},
+		{"since", HistoryFilter{Since: day(3)}, []string{"Parser tests", "Landing page"}},
+		{"until is exclusive", HistoryFilter{Until: day(3)}, []string{"Write the parser", "Fix the daemon"}},
+		{"range", HistoryFilter{Since: day(2), Until: day(4)}, []string{"Fix the daemon", "Parser tests"}},
+		{"empty range", HistoryFilter{Since: day(4), Until: day(2)}, []string{}},
+		{"match ignores case", HistoryFilter{Match: "PARSER"}, []string{"Write the parser", "Parser tests"}},
+		{"goal ignores case", HistoryFilter{Goal: "att"}, []string{"Fix the daemon", "Parser tests"}},
+		{"no match", HistoryFilter{Match: "nothing"}, []string{}},
+		{"sort ascending", HistoryFilter{Sort: "elapsed"}, []string{"Fix the daemon", "Landing page", "Parser tests", "Write the parser"}},
+		{"sort descending", HistoryFilter{Sort: "-createdAt"}, []string{"Landing page", "Parser tests", "Fix the daemon", "Write the parser"}},
+		{"sort is stable", HistoryFilter{Sort: "-time"}, []string{"Write the parser", "Landing page", "Parser tests", "Fix the daemon"}},
+		{"first page", HistoryFilter{Limit: 3}, []string{"Write the parser", "Fix the daemon", "Parser tests"}},
+		{"second page", HistoryFilter{Limit: 3, Page: 2}, []string{"Landing page"}},
+		{"past the last page", HistoryFilter{Limit: 3, Page: 3}, []string{}},
+		{"filter, sort and page", HistoryFilter{Since: day(2), Sort: "work", Limit: 2, Page: 1}, []string{"Fix the daemon", "Landing page"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := works(history.Filter(tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter(%+v) = %q, want %q", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"", false},
+		{"createdAt", false},
+		{"-elapsed", false},
+		{"work", false},
+		{"duration", true},
+		{"--time", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateSort(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSort(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import "time"
+
+// Status is the response of the /status endpoint
+type Status struct {
+	ActiveSessions    int  `json:"activeSessions"`
+	AirtableConnected bool `json:"airtableConnected"`
+	SlackConnected    bool `json:"slackConnected"`
+}
+
+// Session is the latest session of a user as returned by /api/session
+type Session struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Time      int       `json:"time"`
+	Elapsed   int       `json:"elapsed"`
+	Remaining int       `json:"remaining"`
+	EndTime   time.Time `json:"endTime"`
+	Goal      string    `json:"goal"`
+	Work      string    `json:"work,omitempty"`
+	Paused    bool      `json:"paused"`
+	Completed bool      `json:"completed"`
+	MessageTs string    `json:"messageTs,omitempty"`
+}
+
+// SessionAction is the response of the start, pause and cancel endpoints
+type SessionAction struct {
+	ID        string    `json:"id"`
+	SlackID   string    `json:"slackId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Paused    bool      `json:"paused"`
+}
+
+// Stats is the response of /api/stats
+type Stats struct {
+	Sessions int `json:"sessions"`
+	Total    int `json:"total"`
+}
+
+// Goal is a single goal of a user
+type Goal struct {
+	Name    string `json:"name"`
+	Minutes int    `json:"minutes"`
+}
+
+// Goals is the response of /api/goals
+type Goals []Goal
+
+// HistoryEntry is a single past session of a user
+type HistoryEntry struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Time      int       `json:"time"`
+	Elapsed   int       `json:"elapsed"`
+	Goal      string    `json:"goal"`
+	Ended     bool      `json:"ended"`
+	Work      string    `json:"work"`
+}
+
+// History is the response of /api/history
+type History []HistoryEntry
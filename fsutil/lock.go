@@ -0,0 +1,29 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+)
+
+// LockFile takes an exclusive advisory lock on path+".lock", blocking until
+// it is available. The lock lives in a separate file so the data file itself
+// can be replaced while the lock is held. Call the returned function to
+// release it.
+//
+// lockFD and unlockFD are implemented per platform. unlockFD releases the
+// lock and closes the file.
+func LockFile(path string) (func() error, error) {
+	lockFile, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file: %w", err)
+	}
+
+	if err := lockFD(lockFile); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("unable to lock %s: %w", path, err)
+	}
+
+	return func() error {
+		return unlockFD(lockFile)
+	}, nil
+}
@@ -0,0 +1,57 @@
+package fsutil
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Solaris has no flock, fcntl record locks on the whole file are used
+// instead. They belong to the process rather than the file descriptor, so
+// they do not exclude goroutines of the same process, and closing any
+// descriptor of the file releases them. A mutex per lock file serializes the
+// lockers within the process, and is held until the file is closed.
+var processLocks = struct {
+	sync.Mutex
+	byPath map[string]*sync.Mutex
+}{byPath: make(map[string]*sync.Mutex)}
+
+// processLock returns the in-process mutex of a lock file
+func processLock(path string) *sync.Mutex {
+	processLocks.Lock()
+	defer processLocks.Unlock()
+	m := processLocks.byPath[path]
+	if m == nil {
+		m = &sync.Mutex{}
+		processLocks.byPath[path] = m
+	}
+	return m
+}
+
+func lockFD(f *os.File) error {
+	m := processLock(f.Name())
+	m.Lock()
+	lock := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	for {
+		err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLKW, &lock)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			f.Close()
+			m.Unlock()
+		}
+		return err
+	}
+}
+
+func unlockFD(f *os.File) error {
+	lock := syscall.Flock_t{Type: syscall.F_UNLCK, Whence: io.SeekStart}
+	err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lock)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	processLock(f.Name()).Unlock()
+	return err
+}
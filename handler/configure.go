@@ -1,32 +1,178 @@
 package handler
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
-	"path/filepath"
+
 	"att/utils"
 )
 
-// UpdateConfigData updates the configuration data
-func UpdateConfigData(key string, value string) {
-	configData := utils.LoadConfigData()
-	configData[key] = value
-	saveConfigData(configData)
+// profileInfo describes a profile in "att configure profile list"
+type profileInfo struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	SlackID string `json:"slackId"`
+	BaseURL string `json:"baseUrl,omitempty"`
+}
+
+// ApplySettings reports the warnings and the migration of the config file
+// on stderr and applies its output default unless outputSet is true. A
+// config file that can not be loaded is reported by the command itself.
+func ApplySettings(outputSet bool) {
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return
+	}
+	if config.Backup != "" {
+		fmt.Fprintf(os.Stderr, "Migrated the config file to version %d, the old file was saved as %s\n", utils.ConfigVersion, config.Backup)
+	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if !outputSet && config.Output.Format != "" {
+		Output = config.Output.Format
+	}
+}
+
+// UpdateConfigData updates the configuration data of the active profile.
+// Use SetAPIToken for the API token.
+func UpdateConfigData(key string, value string) error {
+	return utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		name := config.ActiveProfile()
+		configData, err := config.ProfileData(name)
+		if err != nil {
+			return err
+		}
+		configData[key] = value
+		config.Profiles[name] = configData
+		return nil
+	})
+}
+
+// SetAPIToken stores the API token of the active profile in the secret
+// store and moves the plaintext tokens of all profiles there too
+func SetAPIToken(token string) error {
+	store, err := utils.SecretStore()
+	if err != nil {
+		return err
+	}
+
+	return utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		if token != "" {
+			name := config.ActiveProfile()
+			configData, err := config.ProfileData(name)
+			if err != nil {
+				return err
+			}
+			if err := store.Set(name, token); err != nil {
+				return fmt.Errorf("unable to store the API token in the %s store: %w", store.Name(), err)
+			}
+			delete(configData, "api-token")
+			config.Profiles[name] = configData
+		}
+
+		moved := 0
+		for _, name := range config.ProfileNames() {
+			plaintext := config.Profiles[name]["api-token"]
+			if plaintext == "" {
+				continue
+			}
+			if err := store.Set(name, plaintext); err != nil {
+				return fmt.Errorf("unable to store the API token in the %s store: %w", store.Name(), err)
+			}
+			delete(config.Profiles[name], "api-token")
+			moved++
+		}
+		if moved > 0 {
+			fmt.Fprintf(os.Stderr, "Moved %d plaintext API token(s) to the %s store\n", moved, store.Name())
+		}
+		return nil
+	})
+}
+
+// AddProfile creates a new profile with the given settings. The API token
+// goes to the secret store.
+func AddProfile(name string, settings map[string]string) error {
+	return utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		if _, ok := config.Profiles[name]; ok {
+			return fmt.Errorf("profile %q already exists", name)
+		}
+
+		configData := make(map[string]string)
+		for key, value := range settings {
+			if value != "" && key != "api-token" {
+				configData[key] = value
+			}
+		}
+		if token := settings["api-token"]; token != "" {
+			store, err := utils.SecretStore()
+			if err != nil {
+				return err
+			}
+			if err := store.Set(name, token); err != nil {
+				return fmt.Errorf("unable to store the API token in the %s store: %w", store.Name(), err)
+			}
+		}
+
+		config.Profiles[name] = configData
+		return nil
+	})
+}
+
+// UseProfile makes a profile the one used when no other is selected
+func UseProfile(name string) error {
+	return utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		if _, err := config.ProfileData(name); err != nil {
+			return err
+		}
+		config.CurrentProfile = name
+		return nil
+	})
 }
 
-// saveConfigData saves the configuration data to a file
-func saveConfigData(configData map[string]string) {
-	configDir, err := os.UserConfigDir()
-	utils.HandleError("Unable to get user config directory", err)
+// ListProfiles prints the configured profiles
+func ListProfiles() error {
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return err
+	}
 
-	configFilePath := filepath.Join(configDir, "att_config.json")
-	configFile, err := os.Create(configFilePath)
-	utils.HandleError("Unable to create config file", err)
-	defer configFile.Close()
+	active := config.ActiveProfile()
+	profiles := []profileInfo{}
+	for _, name := range config.ProfileNames() {
+		profiles = append(profiles, profileInfo{
+			Name:    name,
+			Current: name == active,
+			SlackID: config.Profiles[name]["slack-id"],
+			BaseURL: config.Profiles[name]["base-url"],
+		})
+	}
+	return render(profiles)
+}
 
-	configBytes, err := json.MarshalIndent(configData, "", "  ")
-	utils.HandleError("Unable to marshal config data", err)
+// RemoveProfile deletes a profile. Removing the current profile switches
+// back to the default one.
+func RemoveProfile(name string) error {
+	err := utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		if _, ok := config.Profiles[name]; !ok {
+			return fmt.Errorf("profile %q does not exist", name)
+		}
+		delete(config.Profiles, name)
+		if config.CurrentProfile == name {
+			config.CurrentProfile = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-	_, err = configFile.Write(configBytes)
-	utils.HandleError("Unable to write config data to file", err)
+	store, err := utils.SecretStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(name); err != nil {
+		return fmt.Errorf("unable to delete the API token from the %s store: %w", store.Name(), err)
+	}
+	return nil
 }
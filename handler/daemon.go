@@ -0,0 +1,520 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"att/client"
+	"att/ipc"
+	"att/output"
+	"att/utils"
+)
+
+// daemonBinary returns the path of attd: the one in PATH, or the one next to
+// the att executable
+func daemonBinary() (string, error) {
+	name := "attd"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	if executable, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(executable), name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("attd was not found in PATH or next to att")
+}
+
+// daemonArgs returns the arguments attd is started with for a profile,
+// passing on the --config and --base-url flags of att
+func daemonArgs(profile string) []string {
+	var args []string
+	if profile != "" && profile != utils.DefaultProfile {
+		args = append(args, "-profile", profile)
+	}
+	if utils.ConfigPath != "" {
+		path, err := filepath.Abs(utils.ConfigPath)
+		if err != nil {
+			path = utils.ConfigPath
+		}
+		args = append(args, "-config", path)
+	}
+	if BaseURL != "" {
+		args = append(args, "-base-url", BaseURL)
+	}
+	return args
+}
+
+// installDaemon registers attd as a systemd user service and starts it.
+// Without systemd attd is started in the background instead. It returns a
+// description of what was done.
+func installDaemon(profile string) (string, error) {
+	path, err := daemonBinary()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "linux" {
+		if _, err := exec.LookPath("systemctl"); err == nil {
+			unitPath, err := installSystemdUnit(path, daemonArgs(profile))
+			if err == nil {
+				return "installed and started the systemd user service " + unitPath, nil
+			}
+			fmt.Fprintf(os.Stderr, "Unable to install the systemd user service, starting attd directly: %v\n", err)
+		}
+	}
+
+	pid, err := spawnDaemon(path, daemonArgs(profile))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("started attd in the background (pid %d)", pid), nil
+}
+
+// installSystemdUnit writes the attd user unit and enables it
+func installSystemdUnit(path string, args []string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get user config directory: %w", err)
+	}
+	unitPath := filepath.Join(configDir, "systemd", "user", "attd.service")
+	if err := os.MkdirAll(filepath.Dir(unitPath), 0755); err != nil {
+		return "", fmt.Errorf("unable to create %s: %w", filepath.Dir(unitPath), err)
+	}
+
+	quoted := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{path}, args...) {
+		quoted = append(quoted, systemdQuote(arg))
+	}
+	execStart := strings.Join(quoted, " ")
+	unit := "[Unit]\nDescription=Arcade Time Tracker daemon\n\n" +
+		"[Service]\nExecStart=" + execStart + "\nRestart=on-failure\n\n" +
+		"[Install]\nWantedBy=default.target\n"
+	if err := utils.WriteFileAtomic(unitPath, []byte(unit), 0644); err != nil {
+		return "", err
+	}
+
+	for _, args := range [][]string{{"--user", "daemon-reload"}, {"--user", "enable", "--now", "attd.service"}} {
+		if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+			os.Remove(unitPath)
+			return "", fmt.Errorf("systemctl %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+	}
+	return unitPath, nil
+}
+
+// systemdQuote quotes an ExecStart argument, escaping the characters
+// systemd would otherwise read as escapes, specifiers or variables
+func systemdQuote(arg string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "%", "%%", "$", "$$")
+	return `"` + replacer.Replace(arg) + `"`
+}
+
+// spawnDaemon starts attd detached from the terminal, logging to attd.log
+// in the att state directory, and returns its pid
+func spawnDaemon(path string, args []string) (int, error) {
+	stateDir, err := utils.StateDir()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return 0, fmt.Errorf("unable to create %s: %w", stateDir, err)
+	}
+	logPath, err := daemonLogPath()
+	if err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open the attd log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("unable to start attd: %w", err)
+	}
+	pid := cmd.Process.Pid
+	return pid, cmd.Process.Release()
+}
+
+// Daemon enables handing session actions to a running attd
+var Daemon = true
+
+// ErrDaemonNotRunning is returned when attd does not answer on its socket
+var ErrDaemonNotRunning = errors.New("attd is not running")
+
+// daemonWait is how long start and stop wait for attd
+const daemonWait = 5 * time.Second
+
+// daemonTracker is a session attd sends notifications for
+type daemonTracker struct {
+	Profile   string    `json:"profile,omitempty"`
+	SlackID   string    `json:"slackId"`
+	SessionID string    `json:"sessionId"`
+	CreatedAt time.Time `json:"createdAt"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// daemonInfo is the result of the daemon request of attd
+type daemonInfo struct {
+	PID       int             `json:"pid"`
+	StartedAt time.Time       `json:"startedAt"`
+	Socket    string          `json:"socket"`
+	HTTP      string          `json:"http,omitempty"`
+	Trackers  []daemonTracker `json:"trackers"`
+}
+
+// daemonStatus is printed by the daemon commands
+type daemonStatus struct {
+	PID       int             `json:"pid"`
+	StartedAt time.Time       `json:"startedAt"`
+	Uptime    string          `json:"uptime"`
+	Socket    string          `json:"socket"`
+	HTTP      string          `json:"http,omitempty"`
+	LogFile   string          `json:"logFile,omitempty"`
+	Trackers  []daemonTracker `json:"trackers"`
+}
+
+// daemonSocket returns the path of the attd socket, see the daemon.socket
+// setting
+func daemonSocket() (string, error) {
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return "", err
+	}
+	if config.Daemon.Socket != "" {
+		return config.Daemon.Socket, nil
+	}
+	return utils.SocketPath()
+}
+
+// dialDaemon connects to attd, failing with ErrDaemonNotRunning when
+// nothing answers on its socket
+func dialDaemon() (*ipc.Client, error) {
+	path, err := daemonSocket()
+	if err != nil {
+		return nil, err
+	}
+	d, err := ipc.Dial(path, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%w: nothing is listening on %s", ErrDaemonNotRunning, path)
+	}
+	return d, nil
+}
+
+// queryDaemon asks attd for its pid, uptime and trackers
+func queryDaemon() (*daemonInfo, error) {
+	d, err := dialDaemon()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	d.SetDeadline(time.Now().Add(daemonWait))
+
+	var info daemonInfo
+	if err := d.Call("daemon", nil, &info); err != nil {
+		return nil, fmt.Errorf("unable to query attd: %w", err)
+	}
+	return &info, nil
+}
+
+// daemonActionData is the data of a session action sent to attd. attd
+// reads the credentials of the profile itself, and refuses the action when
+// it would send it to another base URL or for another Slack ID than the CLI.
+type daemonActionData struct {
+	Profile         string `json:"profile,omitempty"`
+	BaseURL         string `json:"base_url,omitempty"`
+	ExpectedSlackID string `json:"expected_slack_id,omitempty"`
+	Work            string `json:"work,omitempty"`
+}
+
+// daemonRunning reports whether session actions may be handed to attd: it
+// answers on its socket, Daemon is on and the credentials are the ones of
+// the profile, which is all attd knows about
+func daemonRunning() bool {
+	if !Daemon || utils.CredentialsOverridden() {
+		return false
+	}
+	d, err := dialDaemon()
+	if err != nil {
+		return false
+	}
+	d.Close()
+	return true
+}
+
+// runWithDaemon hands a session action to a running attd, which performs it
+// with the credentials of the active profile and starts or stops the
+// notifications of the session. It reports false when the action has to be
+// performed directly, see callDaemon.
+func runWithDaemon(c *client.Client, command, work string) (*client.SessionAction, bool, error) {
+	var action client.SessionAction
+	handled, err := callDaemon(c, command, work, &action)
+	if !handled || err != nil {
+		return nil, handled, err
+	}
+	if command == "start" {
+		fmt.Fprintln(os.Stderr, "Started the session with attd, which sends its notifications")
+	}
+	return &action, true, nil
+}
+
+// callDaemon sends a command for the active profile to a running attd and
+// decodes its result into result. It reports false when the command has to
+// be performed directly: attd does not run, Daemon is off, the credentials
+// are overridden or attd uses another base URL or Slack ID for the profile.
+func callDaemon(c *client.Client, command, work string, result interface{}) (bool, error) {
+	if !Daemon || utils.CredentialsOverridden() {
+		return false, nil
+	}
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return false, err
+	}
+	d, err := dialDaemon()
+	if err != nil {
+		return false, nil
+	}
+	defer d.Close()
+
+	data := daemonActionData{Profile: config.ActiveProfile(), BaseURL: c.BaseURL, ExpectedSlackID: c.SlackID, Work: work}
+	if err := d.Call(command, data, result); err != nil {
+		var ipcErr *ipc.Error
+		if errors.As(err, &ipcErr) && (ipcErr.Code == ipc.CodeBaseURLMismatch || ipcErr.Code == ipc.CodeSlackIDMismatch) {
+			return false, nil
+		}
+		return true, err
+	}
+	return true, nil
+}
+
+// daemonLogPath returns the path of the log of attd when att starts it
+func daemonLogPath() (string, error) {
+	stateDir, err := utils.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "attd.log"), nil
+}
+
+// newDaemonStatus returns the printed form of the answer of attd
+func newDaemonStatus(info *daemonInfo) daemonStatus {
+	status := daemonStatus{
+		PID:       info.PID,
+		StartedAt: info.StartedAt,
+		Uptime:    time.Since(info.StartedAt).Round(time.Second).String(),
+		Socket:    info.Socket,
+		HTTP:      info.HTTP,
+		Trackers:  info.Trackers,
+	}
+	if status.Trackers == nil {
+		status.Trackers = []daemonTracker{}
+	}
+	if path, err := daemonLogPath(); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			status.LogFile = path
+		}
+	}
+	return status
+}
+
+// DaemonStatus prints the pid, uptime, socket and trackers of attd
+func DaemonStatus() error {
+	info, err := queryDaemon()
+	if err != nil {
+		return err
+	}
+	return render(newDaemonStatus(info))
+}
+
+// StartDaemon starts attd in the background for the active profile and
+// waits until it answers
+func StartDaemon() error {
+	if info, err := queryDaemon(); err == nil {
+		fmt.Fprintf(os.Stderr, "attd is already running (pid %d)\n", info.PID)
+		return render(newDaemonStatus(info))
+	}
+
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return err
+	}
+	path, err := daemonBinary()
+	if err != nil {
+		return err
+	}
+	pid, err := spawnDaemon(path, daemonArgs(config.ActiveProfile()))
+	if err != nil {
+		return err
+	}
+
+	for deadline := time.Now().Add(daemonWait); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if info, err := queryDaemon(); err == nil {
+			fmt.Fprintf(os.Stderr, "Started attd (pid %d)\n", info.PID)
+			return render(newDaemonStatus(info))
+		}
+	}
+	logPath, _ := daemonLogPath()
+	return fmt.Errorf("attd (pid %d) did not answer within %s, see %s", pid, daemonWait, logPath)
+}
+
+// StopDaemon stops attd and waits until it exits. When attd does not answer
+// on its socket, the process of its pid file is only stopped when it is
+// attd; otherwise the stale pid file is removed.
+func StopDaemon() error {
+	info, err := queryDaemon()
+	if err == nil {
+		return stopDaemonProcess(info.PID, func() bool {
+			d, err := dialDaemon()
+			if err != nil {
+				return true
+			}
+			d.Close()
+			return false
+		})
+	}
+
+	pidFile, pathErr := utils.PIDFilePath()
+	if pathErr != nil {
+		return err
+	}
+	data, readErr := ioutil.ReadFile(pidFile)
+	if readErr != nil {
+		return err
+	}
+	pid, readErr := strconv.Atoi(strings.TrimSpace(string(data)))
+	if readErr != nil || !isDaemonProcess(pid) {
+		os.Remove(pidFile)
+		return fmt.Errorf("%w: removed the stale pid file %s", err, pidFile)
+	}
+	return stopDaemonProcess(pid, func() bool {
+		return !processAlive(pid)
+	})
+}
+
+// stopDaemonProcess stops the attd process pid and waits until stopped
+// reports that it is gone
+func stopDaemonProcess(pid int, stopped func() bool) error {
+	if err := terminate(pid); err != nil {
+		return fmt.Errorf("unable to stop attd (pid %d): %w", pid, err)
+	}
+	for deadline := time.Now().Add(daemonWait); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if stopped() {
+			fmt.Fprintf(os.Stderr, "Stopped attd (pid %d)\n", pid)
+			return nil
+		}
+	}
+	return fmt.Errorf("attd (pid %d) did not stop within %s", pid, daemonWait)
+}
+
+// RestartDaemon stops attd when it runs and starts it again
+func RestartDaemon() error {
+	if err := StopDaemon(); err != nil && !errors.Is(err, ErrDaemonNotRunning) {
+		return err
+	}
+	return StartDaemon()
+}
+
+// httpToken is printed by DaemonToken
+type httpToken struct {
+	Token string `json:"token"`
+	Path  string `json:"path"`
+}
+
+// DaemonToken prints the bearer token of the attd HTTP API, creating it on
+// first use or replacing it with rotate
+func DaemonToken(rotate bool) error {
+	path, err := utils.HTTPTokenPath()
+	if err != nil {
+		return err
+	}
+	var token string
+	if rotate {
+		token, err = utils.RotateHTTPToken()
+	} else {
+		token, err = utils.HTTPToken()
+	}
+	if err != nil {
+		return err
+	}
+
+	if Output == output.Plain {
+		fmt.Println(token)
+		return nil
+	}
+	return render(httpToken{Token: token, Path: path})
+}
+
+// DaemonLogs prints the last lines of the attd log, and with follow the
+// lines written afterwards until interrupted
+func DaemonLogs(lines int, follow bool) error {
+	if lines < 0 {
+		return &UsageError{Err: fmt.Errorf("--lines must not be negative")}
+	}
+	path, err := daemonLogPath()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("there is no attd log at %s, attd run by systemd logs to the journal: journalctl --user -u attd", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	all := strings.SplitAfter(string(data), "\n")
+	if all[len(all)-1] == "" {
+		all = all[:len(all)-1]
+	}
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	fmt.Print(strings.Join(all, ""))
+	if !follow {
+		return nil
+	}
+
+	// Poll for appended lines, starting over when the log is truncated
+	offset := int64(len(data))
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			os.Stdout.Write(buf[:n])
+			offset += int64(n)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			if offset, err = f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// detach starts the command in its own session so it outlives the terminal
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// terminate asks a process to exit
+func terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// isDaemonProcess reports whether pid is an attd process. Only Linux tells
+// the command of a process through /proc; elsewhere it reports false.
+func isDaemonProcess(pid int) bool {
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || pid <= 0 {
+		return false
+	}
+	name := string(bytes.SplitN(cmdline, []byte{0}, 2)[0])
+	return filepath.Base(name) == "attd"
+}
+
+// processAlive reports whether the process pid still exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
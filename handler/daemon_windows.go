@@ -0,0 +1,44 @@
+//go:build windows
+
+package handler
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	detachedProcess       = 0x00000008
+	createNewProcessGroup = 0x00000200
+)
+
+// detach starts the command without a console so it outlives the terminal
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: detachedProcess | createNewProcessGroup}
+}
+
+// terminate stops a process, Windows has no signal to ask it to exit
+func terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// isDaemonProcess reports whether pid is an attd process. It can not be told
+// on Windows, so a pid file alone never gets a process stopped.
+func isDaemonProcess(pid int) bool {
+	return false
+}
+
+// processAlive reports whether the process pid still exists
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"att/client"
+)
+
+// Exit codes of the att CLI
+const (
+	ExitOK                = 0
+	ExitError             = 1
+	ExitUsage             = 2
+	ExitNotConfigured     = 3
+	ExitUnauthorized      = 4
+	ExitConflict          = 5
+	ExitNetwork           = 6
+	ExitServer            = 7
+	ExitMalformedResponse = 8
+	ExitSessionCancelled  = 10
+	ExitDaemonNotRunning  = 11
+	ExitNoActiveSession   = 12
+	ExitInterrupted       = 130
+)
+
+// UsageError is returned for invalid flags or arguments
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// errorKinds maps error categories to their code name and exit code
+var errorKinds = []struct {
+	err      error
+	code     string
+	exitCode int
+}{
+	{client.ErrNotConfigured, "not_configured", ExitNotConfigured},
+	{client.ErrUnauthorized, "unauthorized", ExitUnauthorized},
+	{client.ErrConflict, "conflict", ExitConflict},
+	{client.ErrNetwork, "network", ExitNetwork},
+	{client.ErrServer, "server", ExitServer},
+	{client.ErrMalformedResponse, "malformed_response", ExitMalformedResponse},
+	{ErrSessionCancelled, "session_cancelled", ExitSessionCancelled},
+	{ErrDaemonNotRunning, "daemon_not_running", ExitDaemonNotRunning},
+	{ErrNoActiveSession, "no_active_session", ExitNoActiveSession},
+	{ErrInterrupted, "interrupted", ExitInterrupted},
+}
+
+// classify returns the code name and exit code of an error
+func classify(err error) (string, int) {
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return "usage", ExitUsage
+	}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.code, kind.exitCode
+		}
+	}
+	return "error", ExitError
+}
+
+// ExitCode returns the process exit code for an error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	_, exitCode := classify(err)
+	return exitCode
+}
+
+// errorMessage returns a user-facing message for an error
+func errorMessage(err error) string {
+	if errors.Is(err, client.ErrNotConfigured) {
+		return "Please set your API token and Slack ID using the configure command."
+	}
+	return err.Error()
+}
+
+// ReportError writes an error to w, as a JSON object when asJSON is set
+func ReportError(w io.Writer, err error, asJSON bool) {
+	code, exitCode := classify(err)
+	if !asJSON {
+		fmt.Fprintln(w, "Error:", errorMessage(err))
+		return
+	}
+
+	var report struct {
+		Error struct {
+			Code     string `json:"code"`
+			Message  string `json:"message"`
+			ExitCode int    `json:"exitCode"`
+		} `json:"error"`
+	}
+	report.Error.Code = code
+	report.Error.Message = errorMessage(err)
+	report.Error.ExitCode = exitCode
+	json.NewEncoder(w).Encode(report)
+}
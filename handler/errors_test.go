@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"att/client"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantCode string
+		want     int
+	}{
+		{nil, "", ExitOK},
+		{errors.New("boom"), "error", ExitError},
+		{&UsageError{Err: errors.New("bad flag")}, "usage", ExitUsage},
+		{fmt.Errorf("start: %w", client.ErrConflict), "conflict", ExitConflict},
+		{ErrSessionCancelled, "session_cancelled", ExitSessionCancelled},
+		{ErrDaemonNotRunning, "daemon_not_running", ExitDaemonNotRunning},
+		{ErrNoActiveSession, "no_active_session", ExitNoActiveSession},
+		{ErrInterrupted, "interrupted", ExitInterrupted},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+		if tt.err == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		ReportError(&buf, tt.err, true)
+		if want := fmt.Sprintf(`"code":%q`, tt.wantCode); !strings.Contains(buf.String(), want) {
+			t.Errorf("ReportError(%v) = %s, want %s", tt.err, buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+
+	"att/ipc"
+	"att/output"
+)
+
+// TailEvents subscribes to the events of attd and prints them as they
+// happen, until attd stops
+func TailEvents() error {
+	d, err := dialDaemon()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	request := ipc.Request{Version: ipc.Version, ID: "events", Command: "subscribe"}
+	if err := d.Send(&request); err != nil {
+		return err
+	}
+	for {
+		response, err := d.Receive()
+		if err != nil {
+			return err
+		}
+		if response.ID != request.ID {
+			continue
+		}
+		if !response.OK {
+			if response.Error == nil {
+				return ipc.Errorf(ipc.CodeError, "attd answered subscribe without a result")
+			}
+			return response.Error
+		}
+		if response.Event == nil {
+			continue
+		}
+		if err := printEvent(response.Event); err != nil {
+			return err
+		}
+	}
+}
+
+// printEvent prints an event on one line, or as a record in the structured
+// output formats. JSON events are printed one per line.
+func printEvent(e *ipc.Event) error {
+	if Output != output.Plain {
+		return output.WriteRecord(os.Stdout, Output, e)
+	}
+
+	line := fmt.Sprintf("%s #%d %s", e.Time.Local().Format("15:04:05"), e.Seq, e.Type)
+	for _, field := range []struct{ name, value string }{
+		{"slack-id", e.SlackID},
+		{"session", e.SessionID},
+		{"work", e.Work},
+		{"command", e.Command},
+		{"code", e.Code},
+	} {
+		if field.value != "" {
+			line += fmt.Sprintf(" %s=%q", field.name, field.value)
+		}
+	}
+	if e.Message != "" {
+		line += ": " + e.Message
+	}
+	fmt.Println(line)
+	return nil
+}
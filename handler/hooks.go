@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	"att/client"
+	"att/utils"
+)
+
+// runHook runs the hook command configured for a session action. Its
+// output goes to stderr so it does not mix with the rendered result, and a
+// failing hook is reported without failing the command. The variables of
+// the hook are prefixed with ATT_HOOK_ so they do not override the
+// credentials of an att command run by the hook.
+func runHook(kind, work string, c *client.Client, action *client.SessionAction) {
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return
+	}
+	command := config.Hooks.Command(kind)
+	if command == "" {
+		return
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"ATT_HOOK_KIND="+kind,
+		"ATT_HOOK_SLACK_ID="+c.SlackID,
+		"ATT_HOOK_WORK="+work,
+		"ATT_HOOK_SESSION_ID="+action.ID,
+		"ATT_HOOK_PAUSED="+strconv.FormatBool(action.Paused),
+	)
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "The %s hook failed: %v\n", kind, err)
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"att/client"
+	"att/output"
+	"att/utils"
+)
+
+// ConfigKeys lists the keys of a profile
+var ConfigKeys = utils.ProfileKeys
+
+// Reveal prints the API token in full instead of redacting it
+var Reveal bool
+
+// ShowOrigin makes ShowConfig report where each value came from
+var ShowOrigin bool
+
+// configOrigin is an effective value and where it came from
+type configOrigin struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+// configView is the effective configuration printed by ShowConfig
+type configView struct {
+	Profile     string `json:"profile"`
+	APIToken    string `json:"apiToken"`
+	SlackID     string `json:"slackId"`
+	BaseURL     string `json:"baseUrl"`
+	SecretStore string `json:"secretStore"`
+}
+
+// credentialCheck is the verdict of ValidateConfig on one credential
+type credentialCheck struct {
+	Key     string `json:"key"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Credential check statuses
+const (
+	checkOK      = "ok"
+	checkMissing = "missing"
+	checkInvalid = "invalid"
+	checkUnknown = "unknown"
+)
+
+// redact hides most of a secret
+func redact(value string) string {
+	if value == "" || Reveal {
+		return value
+	}
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + strings.Repeat("*", len(value)-8) + value[len(value)-4:]
+}
+
+// validateKey checks that key is one of ConfigKeys
+func validateKey(key string) error {
+	for _, k := range ConfigKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return &UsageError{Err: fmt.Errorf("unknown config key %q (expected one of %s)", key, strings.Join(ConfigKeys, ", "))}
+}
+
+// ShowConfig prints the effective configuration of the active profile
+func ShowConfig() error {
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return err
+	}
+	configData, origins, err := utils.LoadConfigDataWithOrigin()
+	if err != nil {
+		return err
+	}
+	baseURL, baseURLOrigin, err := utils.ResolveBaseURLWithOrigin(BaseURL)
+	if err != nil {
+		return err
+	}
+
+	if ShowOrigin {
+		values := []configOrigin{{Key: "profile", Value: config.ActiveProfile(), Origin: profileOrigin(config)}}
+		for _, key := range []string{"api-token", "slack-id"} {
+			value := configData[key]
+			if key == "api-token" {
+				value = redact(value)
+			}
+			origin := origins[key]
+			if origin == "" {
+				origin = "unset"
+			}
+			values = append(values, configOrigin{Key: key, Value: value, Origin: origin})
+		}
+		values = append(values, configOrigin{Key: "base-url", Value: baseURL, Origin: baseURLOrigin})
+		return render(values)
+	}
+
+	view := configView{
+		Profile:  config.ActiveProfile(),
+		APIToken: redact(configData["api-token"]),
+		SlackID:  configData["slack-id"],
+		BaseURL:  baseURL,
+	}
+	if store, err := utils.SecretStore(); err == nil {
+		view.SecretStore = store.Name()
+	}
+	return render(view)
+}
+
+// profileOrigin tells where the active profile was selected
+func profileOrigin(config *utils.ConfigFile) string {
+	switch {
+	case utils.Profile != "":
+		return utils.OriginFlag
+	case os.Getenv(utils.ProfileEnv) != "":
+		return utils.OriginEnv + " " + utils.ProfileEnv
+	case config.CurrentProfile != "":
+		return "config file"
+	}
+	return utils.OriginDefault
+}
+
+// GetConfig prints a single value of the active profile
+func GetConfig(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	configData, err := utils.LoadConfigData()
+	if err != nil {
+		return err
+	}
+
+	value := configData[key]
+	if key == "api-token" {
+		value = redact(value)
+	}
+	if Output == output.Plain {
+		fmt.Println(value)
+		return nil
+	}
+	return render(map[string]string{key: value})
+}
+
+// UnsetConfig removes a value from the active profile
+func UnsetConfig(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	var name string
+	err := utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		name = config.ActiveProfile()
+		configData, err := config.ProfileData(name)
+		if err != nil {
+			return err
+		}
+		delete(configData, key)
+		config.Profiles[name] = configData
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if key == "api-token" {
+		store, err := utils.SecretStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(name); err != nil {
+			return fmt.Errorf("unable to delete the API token from the %s store: %w", store.Name(), err)
+		}
+	}
+	return nil
+}
+
+// ValidateConfig checks the API token and Slack ID of the active profile
+// against the session endpoint and reports each of them
+func ValidateConfig() error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	token, slackID, result := checkCredentials(c)
+	if err := render([]credentialCheck{token, slackID}); err != nil {
+		return err
+	}
+	return result
+}
+
+// checkCredentials calls the session endpoint with the credentials of the
+// client and tells whether each of them is accepted
+func checkCredentials(c *client.Client) (credentialCheck, credentialCheck, error) {
+	token := credentialCheck{Key: "api-token", Status: checkUnknown}
+	slackID := credentialCheck{Key: "slack-id", Status: checkUnknown}
+	if c.APIToken == "" {
+		token.Status = checkMissing
+	}
+	if c.SlackID == "" {
+		slackID.Status = checkMissing
+	}
+	if c.APIToken == "" || c.SlackID == "" {
+		return token, slackID, client.ErrNotConfigured
+	}
+
+	// The session endpoint answers 404 both for an unknown Slack ID and for
+	// a known user without sessions, which only the message tells apart
+	_, err := c.Session()
+	var apiErr *client.APIError
+	switch {
+	case err == nil:
+		token.Status, slackID.Status = checkOK, checkOK
+	case errors.Is(err, client.ErrUnauthorized):
+		token.Status = checkInvalid
+		token.Message = "the server rejected the token"
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && unknownUser(apiErr):
+		token.Status = checkOK
+		slackID.Status = checkInvalid
+		slackID.Message = "the server does not know this Slack ID"
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		token.Status, slackID.Status = checkOK, checkOK
+		err = nil
+	default:
+		token.Message = err.Error()
+		slackID.Message = err.Error()
+	}
+	return token, slackID, err
+}
+
+// unknownUser reports whether a 404 of the session endpoint is about the
+// Slack ID, "User not found", rather than "No sessions found"
+func unknownUser(apiErr *client.APIError) bool {
+	return strings.Contains(strings.ToLower(apiErr.Message), "user")
+}
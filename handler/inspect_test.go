@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"att/client"
+	"att/mockserver"
+	"att/testenv"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	mock := mockserver.New(testenv.Token)
+	// unknownUsers answers the session of every user but U1 like the API
+	// does for Slack IDs it does not know. U1 has no sessions, which the
+	// mock answers with 404 "No sessions found".
+	unknownUsers := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/session/") && r.URL.Path != "/api/session/U1" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ok":false,"error":"User not found"}`))
+			return
+		}
+		mock.ServeHTTP(w, r)
+	})
+	server := httptest.NewServer(unknownUsers)
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		token, slackID   string
+		wantToken        string
+		wantSlackID      string
+		wantErr          bool
+		wantUnauthorized bool
+	}{
+		{"valid without sessions", testenv.Token, "U1", checkOK, checkOK, false, false},
+		{"unknown Slack ID", testenv.Token, "U2", checkOK, checkInvalid, true, false},
+		{"rejected token", "wrong", "U1", checkInvalid, checkUnknown, true, true},
+		{"missing token", "", "U1", checkMissing, checkUnknown, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, slackID, err := checkCredentials(client.New(server.URL, tt.token, tt.slackID))
+			if token.Status != tt.wantToken || slackID.Status != tt.wantSlackID {
+				t.Errorf("statuses = %s, %s, want %s, %s", token.Status, slackID.Status, tt.wantToken, tt.wantSlackID)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := ExitCode(err) == ExitUnauthorized; got != tt.wantUnauthorized {
+				t.Errorf("exit code = %d", ExitCode(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"att/client"
+	"att/utils"
+)
+
+// Answers of the --daemon flag of "att login"
+const (
+	DaemonAsk = "ask"
+	DaemonYes = "yes"
+	DaemonNo  = "no"
+)
+
+// LoginOptions configures the login wizard
+type LoginOptions struct {
+	// Token and SlackID are used instead of prompting when set
+	Token   string
+	SlackID string
+	// NonInteractive never prompts, for provisioning scripts
+	NonInteractive bool
+	// NoVerify saves the credentials without checking them against the API
+	NoVerify bool
+	// Daemon tells whether to install and start attd: ask, yes or no
+	Daemon string
+}
+
+// loginResult is printed by Login
+type loginResult struct {
+	Profile     string `json:"profile"`
+	SlackID     string `json:"slackId"`
+	Verified    bool   `json:"verified"`
+	SecretStore string `json:"secretStore"`
+	Daemon      string `json:"daemon,omitempty"`
+}
+
+// loginAttempts is how often the wizard asks again for rejected credentials
+const loginAttempts = 3
+
+// prompter asks questions on stderr and reads the answers from stdin
+type prompter struct {
+	in *bufio.Reader
+}
+
+// ask prints a question and returns the answer, or current when it is empty
+func (p *prompter) ask(question, current string) (string, error) {
+	if current != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, current)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+	answer, err := p.readLine()
+	if answer == "" {
+		answer = current
+	}
+	return answer, err
+}
+
+// askSecret is like ask but does not echo the answer. An empty answer keeps
+// current without showing it.
+func (p *prompter) askSecret(question, current string) (string, error) {
+	if current != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, redact(current))
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+	if restore, err := utils.DisableEcho(os.Stdin); err == nil {
+		defer fmt.Fprintln(os.Stderr)
+		defer restore()
+
+		// Ctrl-C would end att with echo still off, so the terminal is
+		// restored before exiting
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		done := make(chan struct{})
+		defer close(done)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				restore()
+				fmt.Fprintln(os.Stderr)
+				os.Exit(ExitInterrupted)
+			case <-done:
+			}
+		}()
+	}
+	answer, err := p.readLine()
+	if answer == "" {
+		answer = current
+	}
+	return answer, err
+}
+
+// confirm asks a yes/no question
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	fmt.Fprintf(os.Stderr, "%s [%s]: ", question, choices)
+	answer, err := p.readLine()
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, err
+	case "n", "no":
+		return false, err
+	}
+	return def, err
+}
+
+// readLine reads a trimmed line. A last line without newline is fine, but
+// end of input without an answer is an error.
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", &UsageError{Err: fmt.Errorf("no answer: %w", err)}
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Login asks for the API token and Slack ID of the active profile, checks
+// them against the API and saves them. Values given in opts are not asked
+// for, and the daemon is installed and started on request.
+func Login(opts LoginOptions) error {
+	switch opts.Daemon {
+	case DaemonAsk, DaemonYes, DaemonNo:
+	default:
+		return &UsageError{Err: fmt.Errorf("invalid --daemon value %q (expected ask, yes or no)", opts.Daemon)}
+	}
+
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return err
+	}
+	// A profile that does not exist yet is created by the login
+	name := config.ActiveProfile()
+	current := map[string]string{}
+	if _, ok := config.Profiles[name]; ok || name == utils.DefaultProfile {
+		if current, err = utils.LoadProfileData(name); err != nil {
+			return err
+		}
+	}
+	if opts.Token == "" {
+		opts.Token = os.Getenv(utils.APITokenEnv)
+	}
+	if opts.SlackID == "" {
+		opts.SlackID = os.Getenv(utils.SlackIDEnv)
+	}
+	baseURL, err := utils.ResolveBaseURL(BaseURL)
+	if err != nil {
+		return err
+	}
+
+	interactive := !opts.NonInteractive && utils.IsTerminal(os.Stdin)
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	token, slackID := opts.Token, opts.SlackID
+	if !interactive {
+		if token == "" {
+			token = current["api-token"]
+		}
+		if slackID == "" {
+			slackID = current["slack-id"]
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "Logging in to %s with profile %q\n", baseURL, name)
+	}
+
+	verified := false
+	for attempt := 1; ; attempt++ {
+		if interactive {
+			if opts.Token == "" || attempt > 1 {
+				if token, err = p.askSecret("API token", firstNonEmpty(token, current["api-token"])); err != nil {
+					return err
+				}
+			}
+			if opts.SlackID == "" || attempt > 1 {
+				if slackID, err = p.ask("Slack ID", firstNonEmpty(slackID, current["slack-id"])); err != nil {
+					return err
+				}
+			}
+		}
+		if token == "" || slackID == "" {
+			if interactive {
+				fmt.Fprintln(os.Stderr, "Both the API token and the Slack ID are required")
+				continue
+			}
+			return client.ErrNotConfigured
+		}
+		if opts.NoVerify {
+			break
+		}
+
+		tokenCheck, slackIDCheck, err := checkCredentials(client.New(baseURL, token, slackID))
+		if err == nil {
+			verified = true
+			break
+		}
+		if !interactive || attempt >= loginAttempts {
+			return err
+		}
+		if errors.Is(err, client.ErrNetwork) || errors.Is(err, client.ErrServer) {
+			save, confirmErr := p.confirm(fmt.Sprintf("Unable to verify the credentials: %v. Save them anyway?", err), false)
+			if confirmErr != nil {
+				return confirmErr
+			}
+			if !save {
+				return err
+			}
+			break
+		}
+		for _, check := range []credentialCheck{tokenCheck, slackIDCheck} {
+			if check.Status == checkInvalid {
+				fmt.Fprintf(os.Stderr, "The %s is invalid: %s\n", check.Key, check.Message)
+			}
+		}
+	}
+
+	store, err := saveLogin(name, token, slackID)
+	if err != nil {
+		return err
+	}
+	result := loginResult{Profile: name, SlackID: slackID, Verified: verified, SecretStore: store}
+	if interactive {
+		fmt.Fprintln(os.Stderr, "Saved the credentials")
+	}
+
+	startDaemon := opts.Daemon == DaemonYes
+	if opts.Daemon == DaemonAsk && interactive {
+		if startDaemon, err = p.confirm("Install and start the att daemon for notifications?", true); err != nil {
+			return err
+		}
+	}
+	if startDaemon {
+		if result.Daemon, err = installDaemon(name); err != nil {
+			return err
+		}
+	}
+	return render(result)
+}
+
+// saveLogin stores the credentials in a profile, creating it when needed,
+// and returns the name of the secret store holding the token
+func saveLogin(name, token, slackID string) (string, error) {
+	store, err := utils.SecretStore()
+	if err != nil {
+		return "", err
+	}
+	if err := store.Set(name, token); err != nil {
+		return "", fmt.Errorf("unable to store the API token in the %s store: %w", store.Name(), err)
+	}
+
+	err = utils.UpdateConfigFile(func(config *utils.ConfigFile) error {
+		configData := config.Profiles[name]
+		if configData == nil {
+			configData = make(map[string]string)
+		}
+		delete(configData, "api-token")
+		configData["slack-id"] = slackID
+		config.Profiles[name] = configData
+		return nil
+	})
+	return store.Name(), err
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"att/client"
+	"att/queue"
+	"att/utils"
+)
+
+// Queue enables queueing session actions while the API is unreachable
+var Queue = true
+
+// connect creates an API client and replays queued actions first
+func connect() (*client.Client, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	replayQueue(c)
+	return c, nil
+}
+
+// activeProfile returns the name of the profile session actions are queued for
+func activeProfile() (string, error) {
+	config, err := utils.LoadConfigFile()
+	if err != nil {
+		return "", err
+	}
+	return config.ActiveProfile(), nil
+}
+
+// daemonFlushResult is the result of a flush request to attd
+type daemonFlushResult struct {
+	Replayed int            `json:"replayed"`
+	Results  []queue.Result `json:"results"`
+}
+
+// flushQueue replays the queued actions of the profile and the client. A
+// running attd replays them instead, so it tracks the sessions they start.
+func flushQueue(c *client.Client, profile string) ([]queue.Result, error) {
+	var result daemonFlushResult
+	if handled, err := callDaemon(c, "flush", "", &result); handled || err != nil {
+		return result.Results, err
+	}
+	return queue.Flush(c, profile)
+}
+
+// replayQueue replays the queued actions of the client and reports them on stderr
+func replayQueue(c *client.Client) {
+	if c.SlackID == "" {
+		return
+	}
+	profile, err := activeProfile()
+	if err != nil || !queue.Pending(profile, c) {
+		return
+	}
+
+	results, err := flushQueue(c, profile)
+	for _, result := range results {
+		switch result.Status {
+		case queue.StatusDone:
+			fmt.Fprintf(os.Stderr, "Replayed queued %s action #%d\n", result.Kind, result.ID)
+		case queue.StatusFailed:
+			fmt.Fprintf(os.Stderr, "Dropped queued %s action #%d: %s\n", result.Kind, result.ID, result.Error)
+		case queue.StatusExpired:
+			fmt.Fprintf(os.Stderr, "Dropped queued %s action #%d, it is older than %s\n", result.Kind, result.ID, queue.MaxAge)
+		}
+	}
+	if err != nil && !errors.Is(err, client.ErrNetwork) {
+		fmt.Fprintf(os.Stderr, "Unable to replay queued actions: %v\n", err)
+	}
+}
+
+// runSessionAction performs a start, pause or cancel request. The action is
+// queued instead when the API is unreachable, or when earlier queued actions
+// are still waiting so the order is kept.
+func runSessionAction(kind, work string, do func(c *client.Client) (*client.SessionAction, error)) error {
+	c, err := connect()
+	if err != nil {
+		return err
+	}
+	if c.APIToken == "" || c.SlackID == "" {
+		return client.ErrNotConfigured
+	}
+	profile, err := activeProfile()
+	if err != nil {
+		return err
+	}
+
+	if Queue && queue.Pending(profile, c) {
+		return enqueue(kind, work, profile, c, "earlier actions are still queued")
+	}
+
+	action, err := do(c)
+	if Queue && errors.Is(err, client.ErrNetwork) {
+		return enqueue(kind, work, profile, c, "the API is unreachable")
+	}
+	if err != nil {
+		return err
+	}
+
+	runHook(kind, work, c, action)
+	return render(action)
+}
+
+// enqueue adds a session action to the offline queue and prints it
+func enqueue(kind, work, profile string, c *client.Client, reason string) error {
+	action, err := queue.Add(kind, work, profile, c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Queued %s action #%d because %s, it will be replayed on the next successful command\n", kind, action.ID, reason)
+	return render(action)
+}
+
+// ListQueue prints the queued session actions
+func ListQueue() error {
+	actions, err := queue.List()
+	if err != nil {
+		return err
+	}
+	if actions == nil {
+		actions = []queue.Action{}
+	}
+
+	return render(actions)
+}
+
+// FlushQueue replays the queued session actions and prints the results
+func FlushQueue() error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if c.APIToken == "" || c.SlackID == "" {
+		return client.ErrNotConfigured
+	}
+
+	profile, err := activeProfile()
+	if err != nil {
+		return err
+	}
+
+	results, flushErr := flushQueue(c, profile)
+	if results == nil {
+		results = []queue.Result{}
+	}
+	if err := render(results); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// DropQueue removes queued session actions, all of them when no ID is given
+func DropQueue(ids []int) error {
+	dropped, err := queue.Drop(ids...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Dropped %d queued action(s)\n", dropped)
+	return nil
+}
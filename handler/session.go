@@ -1,217 +1,172 @@
 package handler
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"strings"
+	"os"
+
+	"att/client"
+	"att/output"
+	"att/queue"
 	"att/utils"
 )
 
-const BASE_URL = "https://hackhour.hackclub.com"
+// BaseURL overrides the API base URL when set, see utils.ResolveBaseURL
+var BaseURL string
+
+// Output is the output format of the session commands, see output.Formats
+var Output = output.Plain
+
+// pingResult is the structured form of the /ping answer
+type pingResult struct {
+	Response string `json:"response"`
+}
+
+// render prints a value in the selected output format
+func render(value interface{}) error {
+	return output.Write(os.Stdout, Output, value)
+}
 
-// Helper function to format and print JSON data
-func PrettyPrintJSON(data map[string]interface{}) {
-    var sb strings.Builder
-    for key, value := range data {
-        sb.WriteString(fmt.Sprintf("\"%s\": %v\n", key, value))
-    }
-    fmt.Println(sb.String())
+// newClient creates an API client from the stored configuration
+func newClient() (*client.Client, error) {
+	configData, err := utils.LoadConfigData()
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err := utils.ResolveBaseURL(BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(baseURL, configData["api-token"], configData["slack-id"]), nil
 }
 
 // PingServer pings the server and prints the response
-func PingServer() {
-    url := fmt.Sprintf("%s/ping", BASE_URL)
-    resp, err := utils.MakeAPIRequest("GET", url, nil, "")
-    if err != nil {
-        log.Fatalf("Error: %v", err)
-    }
-    defer resp.Body.Close()
-
-    if resp.StatusCode != http.StatusOK {
-        log.Fatalf("Error: received status code %d", resp.StatusCode)
-    }
-
-    body, err := ioutil.ReadAll(resp.Body)
-    utils.HandleError("Unable to read response body", err)
-
-    fmt.Println(string(body))
+func PingServer() error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	pong, err := c.Ping()
+	if err != nil {
+		return err
+	}
+
+	if Output == output.Plain {
+		fmt.Println(pong)
+		return nil
+	}
+	return render(pingResult{Response: pong})
 }
 
 // FetchAndPrintStatus fetches and prints the status of hack hour
-func FetchAndPrintStatus() {
-    url := fmt.Sprintf("%s/status", BASE_URL)
-    resp, err := utils.MakeAPIRequest("GET", url, nil, "")
-    if err != nil {
-        log.Fatalf("Error: %v", err)
-    }
-    defer resp.Body.Close()
-
-    if resp.StatusCode != http.StatusOK {
-        log.Fatalf("Error: received status code %d", resp.StatusCode)
-    }
-
-    body, err := ioutil.ReadAll(resp.Body)
-    utils.HandleError("Unable to read response body", err)
-
-    var status map[string]interface{}
-    err = json.Unmarshal(body, &status)
-    utils.HandleError("Unable to unmarshal response", err)
-
-    fmt.Println("Status of hack hour (heidi):")
-    fmt.Printf("\"activeSessions\": %v\n", status["activeSessions"])
-    fmt.Printf("\"airtableConnected\": %v\n", status["airtableConnected"])
-    fmt.Printf("\"slackConnected\": %v\n", status["slackConnected"])
+func FetchAndPrintStatus() error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+
+	if Output == output.Plain {
+		fmt.Println("Status of hack hour (heidi):")
+	}
+	return render(status)
 }
 
-// FetchAndPrintData fetches and prints data from the API
-func FetchAndPrintData(endpoint string) {
-    configData := utils.LoadConfigData()
-    apiToken := configData["api-token"]
-    slackID := configData["slack-id"]
-
-    if apiToken == "" || slackID == "" {
-        fmt.Println("Please set your API token and Slack ID using the configure command.")
-        return
-    }
-
-    url := fmt.Sprintf("%s/api/%s/%s", BASE_URL, endpoint, slackID)
-    resp, err := utils.MakeAPIRequest("GET", url, nil, apiToken)
-    if err != nil {
-        log.Fatalf("Error: %v", err)
-    }
-    defer resp.Body.Close()
-
-    if resp.StatusCode != http.StatusOK {
-        log.Fatalf("Error: received status code %d", resp.StatusCode)
-    }
-
-    body, err := ioutil.ReadAll(resp.Body)
-    utils.HandleError("Unable to read response body", err)
-
-    var result map[string]interface{}
-    err = json.Unmarshal(body, &result)
-    utils.HandleError("Unable to unmarshal response", err)
-
-    // Print the response data
-    if result["ok"].(bool) {
-        PrettyPrintJSON(result["data"].(map[string]interface{}))
-    } else {
-        fmt.Println("Error: unable to fetch data")
-    }
+// FetchAndPrintData fetches and prints data from the API, or from the
+// local store when Offline is set
+func FetchAndPrintData(endpoint string) error {
+	data, err := fetchData(endpoint)
+	if err != nil {
+		return err
+	}
+	return render(data)
 }
 
-// StartNewSession starts a new session
-func StartNewSession(work string) {
-    configData := utils.LoadConfigData()
-    apiToken := configData["api-token"]
-    slackID := configData["slack-id"]
-
-    if apiToken == "" || slackID == "" {
-        fmt.Println("Please set your API token and Slack ID using the configure command.")
-        return
-    }
-
-    url := fmt.Sprintf("%s/api/start/%s", BASE_URL, slackID)
-    payload := map[string]string{"work": work}
-    payloadBytes, err := json.Marshal(payload)
-    utils.HandleError("Unable to marshal request payload", err)
-
-    resp, err := utils.MakeAPIRequest("POST", url, payloadBytes, apiToken)
-    utils.HandleError("Unable to make request", err)
-    defer resp.Body.Close()
-
-    body, err := ioutil.ReadAll(resp.Body)
-    utils.HandleError("Unable to read response body", err)
-
-    var result map[string]interface{}
-    err = json.Unmarshal(body, &result)
-    utils.HandleError("Unable to unmarshal response", err)
-
-    if resp.StatusCode != http.StatusOK {
-        if errorMessage, exists := result["error"].(string); exists && errorMessage == "You already have an active session" {
-            fmt.Println("You already have an active session")
-        } else {
-            fmt.Printf("Error: received status code %d with message: %s\n", resp.StatusCode, result["error"])
-        }
-        return
-    }
-
-    if ok, exists := result["ok"].(bool); exists && ok {
-        PrettyPrintJSON(result["data"].(map[string]interface{}))
-    } else {
-        fmt.Println("Error:", result["error"])
-    }
+// FetchAndPrintHistory fetches the session history and prints the entries
+// matching the filter
+func FetchAndPrintHistory(filter client.HistoryFilter) error {
+	data, err := fetchData("history")
+	if err != nil {
+		return err
+	}
+	return render(data.(client.History).Filter(filter))
 }
 
-// PauseOrResumeSession pauses or resumes the current session
-func PauseOrResumeSession() {
-    configData := utils.LoadConfigData()
-    apiToken := configData["api-token"]
-    slackID := configData["slack-id"]
-
-    if apiToken == "" || slackID == "" {
-        fmt.Println("Please set your API token and Slack ID using the configure command.")
-        return
-    }
-
-    url := fmt.Sprintf("%s/api/pause/%s", BASE_URL, slackID)
-    resp, err := utils.MakeAPIRequest("POST", url, nil, apiToken)
-    utils.HandleError("Unable to make request", err)
-    defer resp.Body.Close()
-
-    body, err := ioutil.ReadAll(resp.Body)
-    utils.HandleError("Unable to read response body", err)
-
-    var result map[string]interface{}
-    err = json.Unmarshal(body, &result)
-    utils.HandleError("Unable to unmarshal response", err)
-
-    if resp.StatusCode != http.StatusOK {
-        fmt.Printf("Error: received status code %d with message: %s\n", resp.StatusCode, result["error"])
-        return
-    }
-
-    if ok, exists := result["ok"].(bool); exists && ok {
-        PrettyPrintJSON(result["data"].(map[string]interface{}))
-    } else {
-        fmt.Println("Error:", result["error"])
-    }
+// fetchData fetches the data of an endpoint from the API, or from the local
+// store when Offline is set
+func fetchData(endpoint string) (interface{}, error) {
+	if Offline {
+		return fetchStored(endpoint)
+	}
+
+	c, err := connect()
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	switch endpoint {
+	case "session":
+		data, err = c.Session()
+	case "stats":
+		data, err = c.Stats()
+	case "goals":
+		data, err = c.Goals()
+	case "history":
+		data, err = c.History()
+	default:
+		return nil, fmt.Errorf("unknown endpoint %q", endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	remember(c.SlackID, data)
+	return data, nil
 }
 
-// CancelSession cancels the current session
-func CancelSession() {
-    configData := utils.LoadConfigData()
-    apiToken := configData["api-token"]
-    slackID := configData["slack-id"]
-
-    if apiToken == "" || slackID == "" {
-        fmt.Println("Please set your API token and Slack ID using the configure command.")
-        return
-    }
-
-    url := fmt.Sprintf("%s/api/cancel/%s", BASE_URL, slackID)
-    resp, err := utils.MakeAPIRequest("POST", url, nil, apiToken)
-    utils.HandleError("Unable to make request", err)
-    defer resp.Body.Close()
-
-    body, err := ioutil.ReadAll(resp.Body)
-    utils.HandleError("Unable to read response body", err)
-
-    var result map[string]interface{}
-    err = json.Unmarshal(body, &result)
-    utils.HandleError("Unable to unmarshal response", err)
-
-    if resp.StatusCode != http.StatusOK {
-        fmt.Printf("Error: received status code %d with message: %s\n", resp.StatusCode, result["error"])
-        return
-    }
-
-    if ok, exists := result["ok"].(bool); exists && ok {
-        PrettyPrintJSON(result["data"].(map[string]interface{}))
-    } else {
-        fmt.Println("Error:", result["error"])
-    }
-}
\ No newline at end of file
+// StartNewSession starts a new session. A running attd starts it instead,
+// so its notifications begin right away.
+func StartNewSession(work string) error {
+	return runSessionAction(queue.Start, work, func(c *client.Client) (*client.SessionAction, error) {
+		if action, handled, err := runWithDaemon(c, "start", work); handled || err != nil {
+			return action, err
+		}
+		return c.Start(work)
+	})
+}
+
+// PauseOrResumeSession pauses or resumes the current session. A running
+// attd does it instead, so it stops or restarts the notifications.
+func PauseOrResumeSession() error {
+	return runSessionAction(queue.Pause, "", func(c *client.Client) (*client.SessionAction, error) {
+		if daemonRunning() {
+			// attd pauses and resumes explicitly, the API only toggles
+			session, err := c.Session()
+			if err != nil {
+				return nil, err
+			}
+			command := "pause"
+			if session.Paused {
+				command = "resume"
+			}
+			if action, handled, err := runWithDaemon(c, command, ""); handled || err != nil {
+				return action, err
+			}
+		}
+		return c.Pause()
+	})
+}
+
+// CancelSession cancels the current session. A running attd cancels it
+// instead, so it stops the notifications.
+func CancelSession() error {
+	return runSessionAction(queue.Cancel, "", func(c *client.Client) (*client.SessionAction, error) {
+		if action, handled, err := runWithDaemon(c, "cancel", ""); handled || err != nil {
+			return action, err
+		}
+		return c.Cancel()
+	})
+}
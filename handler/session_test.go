@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"att/client"
+	"att/testenv"
+	"att/utils"
+)
+
+// setupMock points the handlers at a mock server with private att
+// directories and returns a client for checking the session state
+func setupMock(t *testing.T) *client.Client {
+	t.Helper()
+	server := testenv.Serve(t, nil)
+
+	oldBaseURL, oldToken, oldSlackID, oldQueue, oldDaemon := BaseURL, utils.APITokenFlag, utils.SlackIDFlag, Queue, Daemon
+	t.Cleanup(func() {
+		BaseURL, utils.APITokenFlag, utils.SlackIDFlag, Queue, Daemon = oldBaseURL, oldToken, oldSlackID, oldQueue, oldDaemon
+	})
+	BaseURL, utils.APITokenFlag, utils.SlackIDFlag, Queue, Daemon = server.URL, testenv.Token, "U1", false, false
+	return client.New(server.URL, testenv.Token, "U1")
+}
+
+func TestSessionActions(t *testing.T) {
+	c := setupMock(t)
+
+	if err := StartNewSession("make robot"); err != nil {
+		t.Fatalf("StartNewSession: %v", err)
+	}
+	session, err := c.Session()
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if session.Work != "make robot" || session.Paused || session.Completed {
+		t.Fatalf("started session = %+v", session)
+	}
+	if err := StartNewSession("another"); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("second StartNewSession error = %v, want a conflict", err)
+	}
+
+	for _, paused := range []bool{true, false} {
+		if err := PauseOrResumeSession(); err != nil {
+			t.Fatalf("PauseOrResumeSession: %v", err)
+		}
+		if session, err = c.Session(); err != nil {
+			t.Fatalf("Session: %v", err)
+		}
+		if session.Paused != paused {
+			t.Errorf("paused = %v, want %v", session.Paused, paused)
+		}
+	}
+
+	if err := CancelSession(); err != nil {
+		t.Fatalf("CancelSession: %v", err)
+	}
+	if session, err = c.Session(); err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if !session.Completed {
+		t.Errorf("cancelled session = %+v, want completed", session)
+	}
+	if err := CancelSession(); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("second CancelSession error = %v, want a conflict", err)
+	}
+	if code := ExitCode(CancelSession()); code != ExitConflict {
+		t.Errorf("exit code = %d, want %d", code, ExitConflict)
+	}
+}
+
+func TestSessionActionsUnauthorized(t *testing.T) {
+	setupMock(t)
+	utils.APITokenFlag = "wrong-token"
+
+	if err := StartNewSession("make robot"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("StartNewSession error = %v, want unauthorized", err)
+	}
+}
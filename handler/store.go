@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"att/client"
+	"att/store"
+	"att/utils"
+)
+
+// Offline answers the session queries from the local store
+var Offline bool
+
+// syncResult summarizes a sync of the local store
+type syncResult struct {
+	Sessions int       `json:"sessions"`
+	Added    int       `json:"added"`
+	SyncedAt time.Time `json:"syncedAt"`
+}
+
+// SyncStore downloads the session data of the user into the local store
+func SyncStore() error {
+	c, err := connect()
+	if err != nil {
+		return err
+	}
+
+	before, err := store.Load(c.SlackID)
+	if err != nil {
+		return err
+	}
+	known := len(before.History)
+
+	synced, err := store.Sync(c)
+	if err != nil {
+		return err
+	}
+
+	return render(syncResult{
+		Sessions: len(synced.History),
+		Added:    len(synced.History) - known,
+		SyncedAt: synced.SyncedAt,
+	})
+}
+
+// remember merges freshly fetched data into the local store. Failures only
+// cost offline access, so they are reported without failing the command.
+func remember(slackID string, data interface{}) {
+	if err := store.Remember(slackID, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to update the local store: %v\n", err)
+	}
+}
+
+// fetchStored returns the locally stored data of an endpoint
+func fetchStored(endpoint string) (interface{}, error) {
+	configData, err := utils.LoadConfigData()
+	if err != nil {
+		return nil, err
+	}
+	slackID := configData["slack-id"]
+	if slackID == "" {
+		return nil, client.ErrNotConfigured
+	}
+
+	u, err := store.Load(slackID)
+	if err != nil {
+		return nil, err
+	}
+	fetchedAt := u.LastFetched(endpoint)
+	if fetchedAt.IsZero() {
+		return nil, fmt.Errorf("no local %s data yet, run the session sync command while online", endpoint)
+	}
+	fmt.Fprintf(os.Stderr, "Showing local data last fetched at %s\n", fetchedAt.Format(time.RFC3339))
+
+	switch endpoint {
+	case "session":
+		if u.Session == nil {
+			return nil, fmt.Errorf("no session in the local store")
+		}
+		return u.Session, nil
+	case "stats":
+		if u.Stats == nil {
+			return nil, fmt.Errorf("no stats in the local store")
+		}
+		return u.Stats, nil
+	case "goals":
+		if u.Goals == nil {
+			return client.Goals{}, nil
+		}
+		return u.Goals, nil
+	case "history":
+		if u.History == nil {
+			return client.History{}, nil
+		}
+		return u.History, nil
+	}
+	return nil, fmt.Errorf("unknown endpoint %q", endpoint)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"testing"
+
+	"att/client"
+)
+
+func TestFetchStored(t *testing.T) {
+	setupMock(t)
+	oldOffline := Offline
+	t.Cleanup(func() { Offline = oldOffline })
+
+	Offline = true
+	if _, err := fetchData("stats"); err == nil {
+		t.Error("offline stats before any fetch succeeded")
+	}
+
+	// Fetching online keeps a copy of that endpoint only
+	Offline = false
+	if _, err := fetchData("stats"); err != nil {
+		t.Fatalf("online stats: %v", err)
+	}
+	Offline = true
+
+	tests := []struct {
+		endpoint string
+		wantErr  bool
+	}{
+		{"stats", false},
+		{"goals", true},
+		{"history", true},
+	}
+	for _, tt := range tests {
+		data, err := fetchData(tt.endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("offline %s error = %v, wantErr %v", tt.endpoint, err, tt.wantErr)
+		}
+		if _, ok := data.(*client.Stats); tt.endpoint == "stats" && !ok {
+			t.Errorf("offline stats = %#v", data)
+		}
+	}
+
+	if err := SyncStore(); err != nil {
+		t.Fatalf("SyncStore: %v", err)
+	}
+	if data, err := fetchData("history"); err != nil || data == nil {
+		t.Errorf("offline history after a sync = %v, %v", data, err)
+	}
+}
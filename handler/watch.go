@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"att/client"
+	"att/output"
+	"att/utils"
+)
+
+// ErrSessionCancelled is returned by WatchSession when the watched session
+// ends before its time is up
+var ErrSessionCancelled = errors.New("session was cancelled")
+
+// ErrNoActiveSession is returned by WatchSession when there is nothing to watch
+var ErrNoActiveSession = errors.New("no active session")
+
+// ErrInterrupted is returned by WatchSession when the user stops it
+var ErrInterrupted = errors.New("interrupted")
+
+// WatchOptions configure WatchSession
+type WatchOptions struct {
+	// Interval is the time between two polls of the session endpoint
+	Interval time.Duration
+	// Title also shows the remaining time in the terminal title
+	Title bool
+}
+
+// progressWidth is the number of cells of the progress bar
+const progressWidth = 30
+
+// watchView is the state drawn by WatchSession
+type watchView struct {
+	session  *client.Session
+	polledAt time.Time
+	pollErr  error
+	lines    int
+}
+
+// WatchSession shows a live countdown of the current session until it
+// completes, is cancelled or the user interrupts it
+func WatchSession(opts WatchOptions) error {
+	c, err := connect()
+	if err != nil {
+		return err
+	}
+
+	session, err := c.Session()
+	if err != nil {
+		return err
+	}
+	if session.Completed {
+		return ErrNoActiveSession
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// The live view redraws in place, so it needs a terminal
+	live := (Output == output.Plain || Output == output.Table) && utils.IsTerminal(os.Stdout)
+	view := &watchView{session: session, polledAt: time.Now()}
+	if live {
+		fmt.Print("\033[?25l")
+		defer fmt.Print("\033[?25h")
+		if opts.Title {
+			defer fmt.Print("\033]0;\007")
+		}
+	} else if err := render(session); err != nil {
+		return err
+	}
+
+	lastPoll := time.Now()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	poll := time.NewTicker(opts.Interval)
+	defer poll.Stop()
+
+	for {
+		if live {
+			view.draw(opts.Title)
+		}
+
+		select {
+		case <-interrupt:
+			if live {
+				fmt.Println()
+			}
+			return ErrInterrupted
+		case <-tick.C:
+			if view.remaining(time.Now()) > 0 || time.Since(lastPoll) < 5*time.Second {
+				continue
+			}
+			// The countdown ran out, confirm with the API without waiting for the next poll
+		case <-poll.C:
+		}
+
+		lastPoll = time.Now()
+		latest, err := c.Session()
+		view.pollErr = err
+		if err != nil {
+			continue
+		}
+		if latest.ID != session.ID {
+			return finishWatch(live, ErrSessionCancelled)
+		}
+		if !live && (latest.Paused != view.session.Paused || latest.Completed) {
+			if err := render(latest); err != nil {
+				return err
+			}
+		}
+		view.session = latest
+		view.polledAt = time.Now()
+
+		if latest.Completed {
+			if live {
+				view.draw(opts.Title)
+			}
+			if latest.Remaining > 0 {
+				return finishWatch(live, ErrSessionCancelled)
+			}
+			return finishWatch(live, nil)
+		}
+	}
+}
+
+// finishWatch ends the live view, a cancelled session is reported by the caller
+func finishWatch(live bool, err error) error {
+	if live && err == nil {
+		fmt.Println("\nSession completed, you did it!")
+	}
+	return err
+}
+
+// remaining returns the time left at now, frozen while the session is paused
+func (v *watchView) remaining(now time.Time) time.Duration {
+	s := v.session
+	if s.Completed || s.Paused || s.EndTime.IsZero() {
+		return time.Duration(s.Remaining) * time.Minute
+	}
+	remaining := s.EndTime.Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// draw redraws the view in place
+func (v *watchView) draw(title bool) {
+	s := v.session
+	total := time.Duration(s.Time) * time.Minute
+	remaining := v.remaining(time.Now())
+	elapsed := total - remaining
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	state := "running"
+	switch {
+	case s.Completed && s.Remaining > 0:
+		state = "cancelled"
+	case s.Completed:
+		state = "completed"
+	case s.Paused:
+		state = "paused"
+	}
+	if v.pollErr != nil {
+		state += fmt.Sprintf(" (last update %s ago: %v)", time.Since(v.polledAt).Round(time.Second), v.pollErr)
+	}
+
+	progress := 0.0
+	if total > 0 {
+		progress = float64(elapsed) / float64(total)
+	}
+	filled := int(progress * progressWidth)
+
+	work := s.Work
+	if work == "" {
+		work = s.Goal
+	}
+
+	lines := []string{
+		fmt.Sprintf("Work:      %s", work),
+		fmt.Sprintf("Status:    %s", state),
+		fmt.Sprintf("Elapsed:   %s", formatClock(elapsed)),
+		fmt.Sprintf("Remaining: %s", formatClock(remaining)),
+		fmt.Sprintf("[%s%s] %3.0f%%", strings.Repeat("#", filled), strings.Repeat("-", progressWidth-filled), progress*100),
+	}
+
+	var sb strings.Builder
+	if v.lines > 0 {
+		sb.WriteString(fmt.Sprintf("\033[%dA", v.lines))
+	}
+	for _, line := range lines {
+		sb.WriteString("\r\033[K")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	if title {
+		sb.WriteString(fmt.Sprintf("\033]0;att %s left (%s)\007", formatClock(remaining), state))
+	}
+	fmt.Print(sb.String())
+	v.lines = len(lines)
+}
+
+// formatClock formats a duration as h:mm:ss
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,105 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Client is a connection to attd
+type Client struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int
+}
+
+// Dial connects to the attd socket at path
+func Dial(path string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to attd at %s: %w", path, err)
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxMessageSize)
+	return &Client{conn: conn, scanner: scanner}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SetDeadline sets the time after which reads and writes fail
+func (c *Client) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Call sends a request and decodes the result of its response into result,
+// which may be nil. A failed request returns an *Error.
+func (c *Client) Call(command string, data interface{}, result interface{}) error {
+	c.nextID++
+	request := Request{Version: Version, ID: strconv.Itoa(c.nextID), Command: command}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("unable to marshal request data: %w", err)
+		}
+		request.Data = raw
+	}
+	if err := c.Send(&request); err != nil {
+		return err
+	}
+
+	for {
+		response, err := c.Receive()
+		if err != nil {
+			return err
+		}
+		if response.ID != request.ID {
+			continue
+		}
+		if !response.OK {
+			if response.Error == nil {
+				return Errorf(CodeError, "attd answered %s without a result", command)
+			}
+			return response.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("unable to unmarshal attd result: %w", err)
+		}
+		return nil
+	}
+}
+
+// Send writes a request to the connection
+func (c *Client) Send(request *Request) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("unable to marshal request: %w", err)
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("unable to write to attd: %w", err)
+	}
+	return nil
+}
+
+// Receive reads the next response from the connection
+func (c *Client) Receive() (*Response, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("unable to read from attd: %w", err)
+		}
+		return nil, fmt.Errorf("attd closed the connection")
+	}
+	var response Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal attd response: %w", err)
+	}
+	return &response, nil
+}
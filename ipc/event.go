@@ -0,0 +1,44 @@
+package ipc
+
+import "time"
+
+// Types of the events streamed to subscribers
+const (
+	EventSessionStarted   = "session.started"
+	EventSessionPaused    = "session.paused"
+	EventSessionResumed   = "session.resumed"
+	EventSessionCancelled = "session.cancelled"
+	EventSessionCompleted = "session.completed"
+	EventNotification     = "notification"
+	EventAPIError         = "api.error"
+)
+
+// Event is something that happened in attd. After a subscribe request,
+// attd sends every event as a response with the ID of the request and the
+// event instead of a result.
+type Event struct {
+	// Seq numbers the events of a run of attd, starting at 1. Subscribers
+	// see every event in order: one that reads too slowly is disconnected
+	// rather than skipped ahead.
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	SlackID   string    `json:"slackId,omitempty"`
+	SessionID string    `json:"sessionId,omitempty"`
+	// Work is the work description of a started session
+	Work string `json:"work,omitempty"`
+	// Title and Message are the text of a notification, Message also
+	// describes an API error
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Command and Code tell the failed request and the error code of an
+	// API error
+	Command string `json:"command,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// SubscribeResult is the result of a subscribe request
+type SubscribeResult struct {
+	// Seq is the sequence number of the last event before the subscription
+	Seq uint64 `json:"seq"`
+}
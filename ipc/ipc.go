@@ -0,0 +1,130 @@
+// Package ipc implements the protocol spoken on the attd socket: versioned,
+// newline-delimited JSON requests and responses matched by request ID.
+// Several requests may be sent over one connection.
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"att/client"
+)
+
+// Version is the protocol version spoken by attd
+const Version = 1
+
+// MaxMessageSize is the largest message accepted on a connection
+const MaxMessageSize = 1 << 20
+
+// Request is a command sent to attd. Requests without a version are read
+// as the current one.
+type Request struct {
+	Version int             `json:"v"`
+	ID      string          `json:"id"`
+	Command string          `json:"command"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Response answers the request with the same ID, with either a result or
+// an error. The responses streamed after a subscribe request carry an
+// event instead.
+type Response struct {
+	Version int             `json:"v"`
+	ID      string          `json:"id"`
+	OK      bool            `json:"ok"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	Event   *Event          `json:"event,omitempty"`
+}
+
+// Error codes of failed requests. API failures use the code names of the
+// att CLI errors.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeUnsupportedVersion = "unsupported_version"
+	CodeUnknownCommand     = "unknown_command"
+	CodeNotConfigured      = "not_configured"
+	CodeUnauthorized       = "unauthorized"
+	CodeConflict           = "conflict"
+	CodeNetwork            = "network"
+	CodeServer             = "server"
+	CodeMalformedResponse  = "malformed_response"
+	CodeError              = "error"
+	// CodeBaseURLMismatch refuses a request whose base_url differs from
+	// the one attd uses for the profile
+	CodeBaseURLMismatch = "base_url_mismatch"
+	// CodeSlackIDMismatch refuses a request whose expected_slack_id differs
+	// from the Slack ID of the profile
+	CodeSlackIDMismatch = "slack_id_mismatch"
+)
+
+// apiCodes maps the API client errors to their code
+var apiCodes = []struct {
+	err  error
+	code string
+}{
+	{client.ErrNotConfigured, CodeNotConfigured},
+	{client.ErrUnauthorized, CodeUnauthorized},
+	{client.ErrConflict, CodeConflict},
+	{client.ErrNetwork, CodeNetwork},
+	{client.ErrServer, CodeServer},
+	{client.ErrMalformedResponse, CodeMalformedResponse},
+}
+
+// Error is the error object of a failed request
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the API client error matching the code, so errors.Is
+// works the same for errors returned by attd and by the API client
+func (e *Error) Unwrap() error {
+	for _, kind := range apiCodes {
+		if kind.code == e.Code {
+			return kind.err
+		}
+	}
+	return nil
+}
+
+// Errorf returns an Error with a code and a formatted message
+func Errorf(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorFrom converts an error into an Error, classifying API client errors
+func ErrorFrom(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	for _, kind := range apiCodes {
+		if errors.Is(err, kind.err) {
+			return &Error{Code: kind.code, Message: err.Error()}
+		}
+	}
+	return &Error{Code: CodeError, Message: err.Error()}
+}
+
+// NewResponse returns the response to a request with the given result, or
+// the error when err is not nil
+func NewResponse(id string, result interface{}, err error) *Response {
+	response := &Response{Version: Version, ID: id}
+	if err == nil {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr == nil {
+			response.OK = true
+			response.Result = data
+			return response
+		}
+		err = marshalErr
+	}
+	response.Error = ErrorFrom(err)
+	return response
+}
@@ -0,0 +1,236 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"att/client"
+)
+
+// serve accepts one connection on a socket in a temporary directory and
+// answers every request line with the lines returned by answer, closing the
+// connection when it returns nil. It returns the socket path and a channel
+// receiving the request lines.
+func serve(t *testing.T, answer func(request Request) []string) (string, <-chan string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "attd.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+			var request Request
+			if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+				return
+			}
+			answers := answer(request)
+			if answers == nil {
+				return
+			}
+			for _, line := range answers {
+				if _, err := conn.Write([]byte(line + "\n")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return path, lines
+}
+
+// dial connects to a socket of serve
+func dial(t *testing.T, path string) *Client {
+	t.Helper()
+	c, err := Dial(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	return c
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     func(id string) []string
+		wantResult string
+		wantCode   string
+		wantIs     error
+		wantErr    bool
+	}{
+		{
+			name: "result",
+			answer: func(id string) []string {
+				return []string{`{"v":1,"id":"` + id + `","ok":true,"result":{"work":"make robot"}}`}
+			},
+			wantResult: "make robot",
+		},
+		{
+			name: "skips the lines of other requests",
+			answer: func(id string) []string {
+				return []string{
+					`{"v":1,"id":"other","ok":true,"event":{"type":"session.started"}}`,
+					`{"v":1,"id":"` + id + `","ok":true,"result":{"work":"make robot"}}`,
+				}
+			},
+			wantResult: "make robot",
+		},
+		{
+			name: "error",
+			answer: func(id string) []string {
+				return []string{`{"v":1,"id":"` + id + `","ok":false,"error":{"code":"conflict","message":"already paused"}}`}
+			},
+			wantCode: CodeConflict,
+			wantIs:   client.ErrConflict,
+			wantErr:  true,
+		},
+		{
+			name:     "failure without an error",
+			answer:   func(id string) []string { return []string{`{"v":1,"id":"` + id + `","ok":false}`} },
+			wantCode: CodeError,
+			wantErr:  true,
+		},
+		{
+			name:    "malformed line",
+			answer:  func(id string) []string { return []string{`{"v":1,"id":`} },
+			wantErr: true,
+		},
+		{
+			name:    "closed connection",
+			answer:  func(id string) []string { return nil },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := serve(t, func(request Request) []string { return tt.answer(request.ID) })
+			c := dial(t, path)
+
+			var result struct {
+				Work string `json:"work"`
+			}
+			err := c.Call("start", map[string]string{"work": "make robot"}, &result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Work != tt.wantResult {
+				t.Errorf("result = %q, want %q", result.Work, tt.wantResult)
+			}
+			var ipcErr *Error
+			if tt.wantCode != "" && (!errors.As(err, &ipcErr) || ipcErr.Code != tt.wantCode) {
+				t.Errorf("error = %#v, want code %s", err, tt.wantCode)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("error = %v, want %v", err, tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestRequestFraming(t *testing.T) {
+	path, lines := serve(t, func(request Request) []string {
+		return []string{fmt.Sprintf(`{"v":1,"id":%q,"ok":true,"result":%q}`, request.ID, request.Command)}
+	})
+	c := dial(t, path)
+
+	// Several requests share the connection, each on one line with its own ID
+	for i, command := range []string{"status", "session", "daemon"} {
+		var result string
+		if err := c.Call(command, map[string]string{"work": "line one\nline two"}, &result); err != nil {
+			t.Fatalf("Call(%s): %v", command, err)
+		}
+		if result != command {
+			t.Errorf("Call(%s) result = %q", command, result)
+		}
+
+		line := <-lines
+		if strings.Contains(line, "\n") {
+			t.Errorf("request %q spans several lines", line)
+		}
+		var request map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &request); err != nil {
+			t.Fatalf("request %q: %v", line, err)
+		}
+		want := map[string]string{"v": "1", "id": fmt.Sprintf(`"%d"`, i+1), "command": fmt.Sprintf("%q", command)}
+		for key, value := range want {
+			if string(request[key]) != value {
+				t.Errorf("request %s = %s, want %s", key, request[key], value)
+			}
+		}
+	}
+}
+
+func TestReceiveRejectsOversizedLines(t *testing.T) {
+	path, _ := serve(t, func(request Request) []string {
+		return []string{`{"v":1,"id":"` + request.ID + `","ok":true,"result":"` + strings.Repeat("x", MaxMessageSize) + `"}`}
+	})
+	c := dial(t, path)
+	if err := c.Call("history", nil, nil); err == nil {
+		t.Error("Call with a response above MaxMessageSize succeeded")
+	}
+}
+
+func TestNewResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     interface{}
+		err        error
+		wantOK     bool
+		wantResult string
+		wantCode   string
+	}{
+		{"result", map[string]int{"replayed": 2}, nil, true, `{"replayed":2}`, ""},
+		{"coded error", nil, Errorf(CodeBadRequest, "invalid"), false, "", CodeBadRequest},
+		{"API error", nil, fmt.Errorf("start: %w", client.ErrNetwork), false, "", CodeNetwork},
+		{"other error", nil, errors.New("boom"), false, "", CodeError},
+		{"result that does not marshal", make(chan int), nil, false, "", CodeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := NewResponse("7", tt.result, tt.err)
+			if response.Version != Version || response.ID != "7" || response.OK != tt.wantOK || string(response.Result) != tt.wantResult {
+				t.Errorf("response = %+v", response)
+			}
+			if tt.wantCode == "" {
+				if response.Error != nil {
+					t.Errorf("error = %+v, want none", response.Error)
+				}
+				return
+			}
+			if response.Error == nil || response.Error.Code != tt.wantCode {
+				t.Errorf("error = %+v, want code %s", response.Error, tt.wantCode)
+			}
+
+			// The error survives the round trip through JSON with its meaning
+			data, err := json.Marshal(response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded Response
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != nil && errors.Is(tt.err, client.ErrNetwork) != errors.Is(decoded.Error, client.ErrNetwork) {
+				t.Errorf("decoded error %v lost its API error", decoded.Error)
+			}
+		})
+	}
+}
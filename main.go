@@ -2,12 +2,19 @@ package main
 
 import (
     "fmt"
+	"net/http"
     "os"
     "path/filepath"
+	"strconv"
     "strings"
+	"time"
 
-    "github.com/spf13/cobra"
+	"att/client"
     "att/handler"
+	"att/mockserver"
+	"att/output"
+	"att/utils"
+	"github.com/spf13/cobra"
 )
 
 var VERSION = "0.0.1"
@@ -16,7 +23,17 @@ func printASCIIArt() {
 	fmt.Println("      _    _   ")
 	fmt.Println(" ___ | |_ | |_ ")
 	fmt.Println("| .'||  _||  _|")
-  	fmt.Println("|__,||_|  |_|  \n")
+	fmt.Print("|__,||_|  |_|  \n\n")
+}
+
+// usageArgs reports argument validation failures as usage errors
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &handler.UsageError{Err: err}
+		}
+		return nil
+	}
 }
 
 func main() {
@@ -37,6 +54,30 @@ func main() {
 		},
     }
 
+	// Global flags shared by all commands
+	rootCmd.PersistentFlags().StringVar(&handler.BaseURL, "base-url", "", "Hack Hour API base URL (overrides "+utils.BaseURLEnv+" and the config)")
+	rootCmd.PersistentFlags().StringVar(&utils.Profile, "profile", "", "configuration profile to use (overrides "+utils.ProfileEnv+")")
+	rootCmd.PersistentFlags().StringVar(&utils.ConfigPath, "config", "", "path of the configuration file")
+	rootCmd.PersistentFlags().StringVar(&utils.APITokenFlag, "token", "", "API token to use (overrides "+utils.APITokenEnv+" and the profile)")
+	rootCmd.PersistentFlags().StringVar(&utils.SlackIDFlag, "slack-id", "", "Slack ID to use (overrides "+utils.SlackIDEnv+" and the profile)")
+	rootCmd.PersistentFlags().StringVarP(&handler.Output, "output", "o", output.Plain, "output format ("+strings.Join(output.Formats, ", ")+")")
+	rootCmd.PersistentFlags().DurationVar(&utils.RequestTimeout, "timeout", utils.DefaultRequestTimeout, "timeout of a single API request")
+	rootCmd.PersistentFlags().IntVar(&utils.MaxRetries, "retries", utils.DefaultMaxRetries, "number of retries for failed API requests")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		handler.ApplySettings(cmd.Flags().Changed("output"))
+		if err := output.Validate(handler.Output); err != nil {
+			return &handler.UsageError{Err: err}
+		}
+		return nil
+	}
+
+	// Errors are reported by main with a matching exit code
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &handler.UsageError{Err: err}
+	})
+
     // Define the configure command
     var configureCmd = &cobra.Command{
         Use:   "configure",
@@ -46,11 +87,13 @@ func main() {
     // Define the api-token sub-command
     var apiTokenCmd = &cobra.Command{
         Use:   "api-token [token]",
-        Short: "Set the API token",
-        Args:  cobra.ExactArgs(1),
-        Run: func(cmd *cobra.Command, args []string) {
+		Short: "Set the API token, or move plaintext tokens to the secret store when none is given",
+		Args:  usageArgs(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
             apiToken = args[0]
-            handler.UpdateConfigData("api-token", apiToken)
+			}
+			return handler.SetAPIToken(apiToken)
         },
     }
 
@@ -58,16 +101,141 @@ func main() {
     var slackIDCmd = &cobra.Command{
         Use:   "slack-id [id]",
         Short: "Set the Slack ID",
-        Args:  cobra.ExactArgs(1),
-        Run: func(cmd *cobra.Command, args []string) {
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
             slackID = args[0]
-            handler.UpdateConfigData("slack-id", slackID)
+			return handler.UpdateConfigData("slack-id", slackID)
+		},
+	}
+
+	// Define the base-url sub-command
+	var baseURLCmd = &cobra.Command{
+		Use:   "base-url [url]",
+		Short: "Set the Hack Hour API base URL",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.UpdateConfigData("base-url", args[0])
+		},
+	}
+
+	// Define the profile sub-command
+	var profileCmd = &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+	}
+
+	// Define the profile add sub-command
+	var profileSettings = map[string]*string{
+		"api-token": new(string),
+		"slack-id":  new(string),
+		"base-url":  new(string),
+	}
+	var profileAddCmd = &cobra.Command{
+		Use:   "add [name]",
+		Short: "Add a profile",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := make(map[string]string)
+			for key, value := range profileSettings {
+				settings[key] = *value
+			}
+			return handler.AddProfile(args[0], settings)
+		},
+	}
+	profileAddCmd.Flags().StringVar(profileSettings["api-token"], "api-token", "", "API token of the profile")
+	profileAddCmd.Flags().StringVar(profileSettings["slack-id"], "slack-id", "", "Slack ID of the profile")
+	profileAddCmd.Flags().StringVar(profileSettings["base-url"], "base-url", "", "Hack Hour API base URL of the profile")
+
+	// Define the profile use sub-command
+	var profileUseCmd = &cobra.Command{
+		Use:   "use [name]",
+		Short: "Use a profile when no other is selected",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.UseProfile(args[0])
+		},
+	}
+
+	// Define the profile list sub-command
+	var profileListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the profiles",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ListProfiles()
+		},
+	}
+
+	// Define the profile remove sub-command
+	var profileRemoveCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a profile",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.RemoveProfile(args[0])
+		},
+	}
+
+	// Add the sub-commands to the profile command
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+
+	// Define the show sub-command
+	var showCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Show the effective configuration",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ShowConfig()
+		},
+	}
+	showCmd.Flags().BoolVar(&handler.Reveal, "reveal", false, "print the API token in full")
+	showCmd.Flags().BoolVar(&handler.ShowOrigin, "origin", false, "report where each value came from")
+
+	// Define the get sub-command
+	var getCmd = &cobra.Command{
+		Use:       "get [key]",
+		Short:     "Print a configuration value",
+		Args:      usageArgs(cobra.ExactArgs(1)),
+		ValidArgs: handler.ConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.GetConfig(args[0])
+		},
+	}
+	getCmd.Flags().BoolVar(&handler.Reveal, "reveal", false, "print the API token in full")
+
+	// Define the unset sub-command
+	var unsetCmd = &cobra.Command{
+		Use:       "unset [key]",
+		Short:     "Remove a configuration value",
+		Args:      usageArgs(cobra.ExactArgs(1)),
+		ValidArgs: handler.ConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.UnsetConfig(args[0])
+		},
+	}
+
+	// Define the validate sub-command
+	var validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check the API token and Slack ID against the API",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ValidateConfig()
         },
     }
 
     // Add the sub-commands to the configure command
     configureCmd.AddCommand(apiTokenCmd)
     configureCmd.AddCommand(slackIDCmd)
+	configureCmd.AddCommand(baseURLCmd)
+	configureCmd.AddCommand(profileCmd)
+	configureCmd.AddCommand(showCmd)
+	configureCmd.AddCommand(getCmd)
+	configureCmd.AddCommand(unsetCmd)
+	configureCmd.AddCommand(validateCmd)
 
     // Define the session command
     var sessionCmd = &cobra.Command{
@@ -79,8 +247,8 @@ func main() {
     var listCmd = &cobra.Command{
         Use:   "list",
         Short: "List the latest session",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.FetchAndPrintData("session")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.FetchAndPrintData("session")
         },
     }
 
@@ -88,8 +256,8 @@ func main() {
     var statsCmd = &cobra.Command{
         Use:   "stats",
         Short: "Get the stats for the user",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.FetchAndPrintData("stats")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.FetchAndPrintData("stats")
         },
     }
 
@@ -97,28 +265,55 @@ func main() {
     var goalsCmd = &cobra.Command{
         Use:   "goals",
         Short: "Get the goals for the user",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.FetchAndPrintData("goals")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.FetchAndPrintData("goals")
         },
     }
 
     // Define the history sub-command
+	var historySince, historyUntil string
+	var historyFilter client.HistoryFilter
     var historyCmd = &cobra.Command{
         Use:   "history",
         Short: "Get the history for the user",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.FetchAndPrintData("history")
-        },
-    }
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			now := time.Now()
+			if historyFilter.Since, err = utils.ParseTimeSpec(historySince, now); err != nil {
+				return &handler.UsageError{Err: err}
+			}
+			if historyFilter.Until, err = utils.ParseTimeSpec(historyUntil, now); err != nil {
+				return &handler.UsageError{Err: err}
+			}
+			if err := client.ValidateSort(historyFilter.Sort); err != nil {
+				return &handler.UsageError{Err: err}
+			}
+			if historyFilter.Limit < 0 || historyFilter.Page < 0 {
+				return &handler.UsageError{Err: fmt.Errorf("--limit and --page must not be negative")}
+			}
+			if historyFilter.Page > 0 && historyFilter.Limit == 0 {
+				return &handler.UsageError{Err: fmt.Errorf("--page requires --limit")}
+			}
+			return handler.FetchAndPrintHistory(historyFilter)
+		},
+	}
+	historyCmd.Flags().StringVar(&historySince, "since", "", "only sessions created at or after this time (e.g. 2024-07-01 or 7d)")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "only sessions created before this time (e.g. 2024-07-08 or 1d)")
+	historyCmd.Flags().StringVar(&historyFilter.Match, "match", "", "only sessions whose work description contains this text")
+	historyCmd.Flags().StringVar(&historyFilter.Goal, "goal", "", "only sessions of this goal")
+	historyCmd.Flags().StringVar(&historyFilter.Sort, "sort", "", "sort by "+strings.Join(client.HistorySortKeys, ", ")+" (prefix with - for descending)")
+	historyCmd.Flags().IntVar(&historyFilter.Limit, "limit", 0, "number of sessions per page")
+	historyCmd.Flags().IntVar(&historyFilter.Page, "page", 0, "page to show, starting at 1 (requires --limit)")
 
     // Define the start sub-command
     var startCmd = &cobra.Command{
 		Use:   "start [work...]",
 		Short: "Start a new session",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Args:  usageArgs(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
 			work := strings.Join(args, " ")
-			handler.StartNewSession(work)
+			return handler.StartNewSession(work)
 		},
 	}
 
@@ -126,8 +321,8 @@ func main() {
     var pauseCmd = &cobra.Command{
         Use:   "pause",
         Short: "Pause or resume the current session",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.PauseOrResumeSession()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.PauseOrResumeSession()
         },
     }
 
@@ -135,11 +330,47 @@ func main() {
     var cancelCmd = &cobra.Command{
         Use:   "cancel",
         Short: "Cancel the current session",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.CancelSession()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.CancelSession()
+		},
+	}
+
+	// Define the watch sub-command
+	var watchOpts handler.WatchOptions
+	var watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Show a live countdown of the current session",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if watchOpts.Interval < time.Second {
+				return &handler.UsageError{Err: fmt.Errorf("--interval must be at least 1s")}
+			}
+			return handler.WatchSession(watchOpts)
+		},
+	}
+	watchCmd.Flags().DurationVar(&watchOpts.Interval, "interval", 30*time.Second, "time between two polls of the session")
+	watchCmd.Flags().BoolVar(&watchOpts.Title, "title", false, "show the remaining time in the terminal title")
+
+	// Define the sync sub-command
+	var syncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Download the session history into the local store",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.SyncStore()
         },
     }
 
+	var noQueue, noDaemon bool
+	sessionCmd.PersistentFlags().BoolVar(&noQueue, "no-queue", false, "fail instead of queueing actions while the API is unreachable")
+	sessionCmd.PersistentFlags().BoolVar(&noDaemon, "no-daemon", false, "perform session actions directly instead of handing them to a running attd")
+	sessionCmd.PersistentFlags().BoolVar(&handler.Offline, "offline", false, "answer list, stats, goals and history from the local store")
+	sessionCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		handler.Queue = !noQueue
+		handler.Daemon = !noDaemon
+		return rootCmd.PersistentPreRunE(cmd, args)
+	}
+
     // Add the sub-commands to the session command
     sessionCmd.AddCommand(listCmd)
     sessionCmd.AddCommand(statsCmd)
@@ -148,13 +379,176 @@ func main() {
     sessionCmd.AddCommand(startCmd)
     sessionCmd.AddCommand(pauseCmd)
     sessionCmd.AddCommand(cancelCmd)
+	sessionCmd.AddCommand(watchCmd)
+	sessionCmd.AddCommand(syncCmd)
+
+	// Define the queue command
+	var queueCmd = &cobra.Command{
+		Use:   "queue",
+		Short: "Manage session actions queued while offline",
+	}
+
+	// Define the queue list sub-command
+	var queueListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the queued session actions",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ListQueue()
+		},
+	}
+
+	// Define the queue flush sub-command
+	var queueFlushCmd = &cobra.Command{
+		Use:   "flush",
+		Short: "Replay the queued session actions now",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.FlushQueue()
+		},
+	}
+
+	// Define the queue drop sub-command
+	var dropAll bool
+	var queueDropCmd = &cobra.Command{
+		Use:   "drop [id...]",
+		Short: "Remove queued session actions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !dropAll {
+				return &handler.UsageError{Err: fmt.Errorf("pass the IDs to drop or --all")}
+			}
+			ids := make([]int, 0, len(args))
+			for _, arg := range args {
+				id, err := strconv.Atoi(arg)
+				if err != nil {
+					return &handler.UsageError{Err: fmt.Errorf("invalid action ID %q", arg)}
+				}
+				ids = append(ids, id)
+			}
+			return handler.DropQueue(ids)
+		},
+	}
+	queueDropCmd.Flags().BoolVar(&dropAll, "all", false, "drop every queued action")
+
+	// Add the sub-commands to the queue command
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueFlushCmd)
+	queueCmd.AddCommand(queueDropCmd)
+
+	// Define the login command
+	var loginOpts handler.LoginOptions
+	var loginCmd = &cobra.Command{
+		Use:   "login",
+		Short: "Set up and verify the API token and Slack ID",
+		Long:  "Asks for the API token and Slack ID of the active profile, verifies them against the API and saves them. The global --token and --slack-id flags, or ATT_API_TOKEN and ATT_SLACK_ID, skip the questions.",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loginOpts.Token = utils.APITokenFlag
+			loginOpts.SlackID = utils.SlackIDFlag
+			return handler.Login(loginOpts)
+		},
+	}
+	loginCmd.Flags().BoolVar(&loginOpts.NonInteractive, "non-interactive", false, "never prompt, fail when a value is missing or invalid")
+	loginCmd.Flags().BoolVar(&loginOpts.NoVerify, "no-verify", false, "save the credentials without checking them against the API")
+	loginCmd.Flags().StringVar(&loginOpts.Daemon, "daemon", handler.DaemonAsk, "install and start the att daemon (ask, yes or no)")
+
+	// Define the daemon command
+	var daemonCmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Manage attd, the daemon sending session notifications",
+	}
+
+	// Define the daemon start sub-command
+	var daemonStartCmd = &cobra.Command{
+		Use:   "start",
+		Short: "Start attd in the background for the active profile",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.StartDaemon()
+		},
+	}
+
+	// Define the daemon stop sub-command
+	var daemonStopCmd = &cobra.Command{
+		Use:   "stop",
+		Short: "Stop attd",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.StopDaemon()
+		},
+	}
+
+	// Define the daemon restart sub-command
+	var daemonRestartCmd = &cobra.Command{
+		Use:   "restart",
+		Short: "Stop attd and start it again",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.RestartDaemon()
+		},
+	}
+
+	// Define the daemon status sub-command
+	var daemonStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the pid, uptime, socket and tracked sessions of attd",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.DaemonStatus()
+		},
+	}
+
+	// Define the daemon logs sub-command
+	var logLines int
+	var followLogs bool
+	var daemonLogsCmd = &cobra.Command{
+		Use:   "logs",
+		Short: "Print the log of attd",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.DaemonLogs(logLines, followLogs)
+		},
+	}
+	daemonLogsCmd.Flags().IntVarP(&logLines, "lines", "n", 50, "number of lines to print")
+	daemonLogsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "keep printing lines as they are written")
+
+	// Define the daemon token sub-command
+	var rotateToken bool
+	var daemonTokenCmd = &cobra.Command{
+		Use:   "token",
+		Short: "Print the bearer token of the attd HTTP API",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.DaemonToken(rotateToken)
+		},
+	}
+	daemonTokenCmd.Flags().BoolVar(&rotateToken, "rotate", false, "replace the token with a new one")
+
+	// Add the sub-commands to the daemon command
+	daemonCmd.AddCommand(daemonStartCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+	daemonCmd.AddCommand(daemonRestartCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonLogsCmd)
+	daemonCmd.AddCommand(daemonTokenCmd)
+
+	// Define the events command
+	var eventsCmd = &cobra.Command{
+		Use:   "events",
+		Short: "Print the events of attd as they happen",
+		Long:  "Subscribes to attd and prints an event whenever a session is started, paused, resumed, cancelled or completed, a notification is sent or a request to the API fails.",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.TailEvents()
+		},
+	}
 
     // Define the ping command
     var pingCmd = &cobra.Command{
         Use:   "ping",
         Short: "Ping the server",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.PingServer()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.PingServer()
         },
     }
 
@@ -162,11 +556,30 @@ func main() {
     var statusCmd = &cobra.Command{
         Use:   "status",
         Short: "Get the status of hack hour",
-        Run: func(cmd *cobra.Command, args []string) {
-            handler.FetchAndPrintStatus()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.FetchAndPrintStatus()
         },
     }
 
+	// Define the mock-server command
+	var mockAddr string
+	var mockToken string
+	var mockSessionLength time.Duration
+	var mockServerCmd = &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run an in-memory Hack Hour API for offline development",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server := mockserver.New(mockToken)
+			server.SessionLength = mockSessionLength
+			fmt.Printf("Mock Hack Hour API listening on http://%s\n", mockAddr)
+			return http.ListenAndServe(mockAddr, server)
+		},
+	}
+	mockServerCmd.Flags().StringVar(&mockAddr, "addr", "127.0.0.1:8080", "address to listen on")
+	mockServerCmd.Flags().StringVar(&mockToken, "token", "", "accepted API token (any token is accepted when empty)")
+	mockServerCmd.Flags().DurationVar(&mockSessionLength, "session-length", mockserver.DefaultSessionLength, "length of new sessions")
+
 	// CLI Version
 	var versionCmd = &cobra.Command{
         Use:   "version",
@@ -178,14 +591,19 @@ func main() {
 
     // Add the configure, session, ping, and status commands to the root command
     rootCmd.AddCommand(configureCmd)
+	rootCmd.AddCommand(loginCmd)
     rootCmd.AddCommand(sessionCmd)
+	rootCmd.AddCommand(queueCmd)
+	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(eventsCmd)
     rootCmd.AddCommand(pingCmd)
     rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(mockServerCmd)
 	rootCmd.AddCommand(versionCmd)
 
     // Execute the root command
     if err := rootCmd.Execute(); err != nil {
-        fmt.Println(err)
-        os.Exit(1)
+		handler.ReportError(os.Stderr, err, handler.Output == output.JSON)
+		os.Exit(handler.ExitCode(err))
     }
 }
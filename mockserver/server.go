@@ -0,0 +1,355 @@
+// Package mockserver implements an in-memory stand-in for the Hack Hour API.
+// It is meant for offline development, demos and end to end tests of the CLI
+// and the daemon.
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSessionLength is the length of a new session
+const DefaultSessionLength = 60 * time.Minute
+
+// DefaultGoal is the goal assigned to new sessions
+const DefaultGoal = "No Goal"
+
+// session is the state of a single session
+type session struct {
+	id        string
+	work      string
+	goal      string
+	createdAt time.Time
+	endTime   time.Time
+	pausedAt  time.Time
+	endedAt   time.Time
+	paused    bool
+	cancelled bool
+	completed bool
+}
+
+// user is the state of a single Slack user
+type user struct {
+	sessions []*session
+	goals    []string
+}
+
+// Server is an in-memory Hack Hour API
+type Server struct {
+	// Token is the accepted bearer token. Any token is accepted when empty.
+	Token string
+	// SessionLength is the length of new sessions
+	SessionLength time.Duration
+	// Now returns the current time, it can be replaced to control the clock
+	Now func() time.Time
+
+	mu     sync.Mutex
+	nextID int
+	users  map[string]*user
+}
+
+// New creates a new mock server accepting the given token
+func New(token string) *Server {
+	return &Server{
+		Token:         token,
+		SessionLength: DefaultSessionLength,
+		Now:           time.Now,
+		users:         make(map[string]*user),
+	}
+}
+
+// ServeHTTP routes a request to the matching endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/ping":
+		w.Write([]byte("pong"))
+		return
+	case "/status":
+		s.handleStatus(w)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "api" || parts[2] == "" {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	endpoint, slackID := parts[1], parts[2]
+
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	method := http.MethodGet
+	switch endpoint {
+	case "start", "pause", "cancel":
+		method = http.MethodPost
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	u := s.user(slackID)
+	s.settle(u)
+
+	switch endpoint {
+	case "session":
+		s.handleSession(w, u)
+	case "stats":
+		s.handleStats(w, u)
+	case "goals":
+		s.handleGoals(w, u)
+	case "history":
+		s.handleHistory(w, u)
+	case "start":
+		s.handleStart(w, r, u, slackID)
+	case "pause":
+		s.handlePause(w, u, slackID)
+	case "cancel":
+		s.handleCancel(w, u, slackID)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// user returns the state of a Slack user, creating it on first use
+func (s *Server) user(slackID string) *user {
+	u, ok := s.users[slackID]
+	if !ok {
+		u = &user{goals: []string{DefaultGoal}}
+		s.users[slackID] = u
+	}
+	return u
+}
+
+// settle marks the active session of a user completed once its end time passed
+func (s *Server) settle(u *user) {
+	current := u.active()
+	if current != nil && !current.paused && !s.Now().Before(current.endTime) {
+		current.completed = true
+	}
+}
+
+// active returns the running or paused session of a user, if any
+func (u *user) active() *session {
+	if len(u.sessions) == 0 {
+		return nil
+	}
+	last := u.sessions[len(u.sessions)-1]
+	if last.completed || last.cancelled {
+		return nil
+	}
+	return last
+}
+
+// elapsed returns the minutes a session has been running, excluding pauses
+func (s *Server) elapsed(sess *session) int {
+	now := s.Now()
+	switch {
+	case sess.cancelled:
+		now = sess.endedAt
+	case sess.paused:
+		now = sess.pausedAt
+	}
+	remaining := sess.endTime.Sub(now)
+	if sess.completed || remaining < 0 {
+		remaining = 0
+	}
+	if remaining > s.SessionLength {
+		remaining = s.SessionLength
+	}
+	return int((s.SessionLength - remaining) / time.Minute)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter) {
+	active := 0
+	for _, u := range s.users {
+		s.settle(u)
+		if u.active() != nil {
+			active++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"activeSessions":    active,
+		"airtableConnected": true,
+		"slackConnected":    true,
+	})
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, u *user) {
+	if len(u.sessions) == 0 {
+		writeError(w, http.StatusNotFound, "No sessions found")
+		return
+	}
+
+	sess := u.sessions[len(u.sessions)-1]
+	length := int(s.SessionLength / time.Minute)
+	elapsed := s.elapsed(sess)
+	writeData(w, map[string]interface{}{
+		"id":        sess.id,
+		"createdAt": sess.createdAt,
+		"time":      length,
+		"elapsed":   elapsed,
+		"remaining": length - elapsed,
+		"endTime":   sess.endTime,
+		"goal":      sess.goal,
+		"work":      sess.work,
+		"paused":    sess.paused,
+		"completed": sess.completed || sess.cancelled,
+		"messageTs": sess.id,
+	})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, u *user) {
+	sessions, total := 0, 0
+	for _, sess := range u.sessions {
+		if sess.completed {
+			sessions++
+		}
+		total += s.elapsed(sess)
+	}
+
+	writeData(w, map[string]interface{}{
+		"sessions": sessions,
+		"total":    total,
+	})
+}
+
+func (s *Server) handleGoals(w http.ResponseWriter, u *user) {
+	goals := make([]map[string]interface{}, 0, len(u.goals))
+	for _, name := range u.goals {
+		minutes := 0
+		for _, sess := range u.sessions {
+			if sess.goal == name {
+				minutes += s.elapsed(sess)
+			}
+		}
+		goals = append(goals, map[string]interface{}{
+			"name":    name,
+			"minutes": minutes,
+		})
+	}
+
+	writeData(w, goals)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, u *user) {
+	history := make([]map[string]interface{}, 0, len(u.sessions))
+	for _, sess := range u.sessions {
+		history = append(history, map[string]interface{}{
+			"createdAt": sess.createdAt,
+			"time":      int(s.SessionLength / time.Minute),
+			"elapsed":   s.elapsed(sess),
+			"goal":      sess.goal,
+			"ended":     sess.completed || sess.cancelled,
+			"work":      sess.work,
+		})
+	}
+
+	writeData(w, history)
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request, u *user, slackID string) {
+	var payload struct {
+		Work string `json:"work"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Work == "" {
+		writeError(w, http.StatusBadRequest, "Missing work")
+		return
+	}
+
+	if u.active() != nil {
+		writeError(w, http.StatusBadRequest, "You already have an active session")
+		return
+	}
+
+	s.nextID++
+	now := s.Now()
+	sess := &session{
+		id:        fmt.Sprintf("mock-%d", s.nextID),
+		work:      payload.Work,
+		goal:      DefaultGoal,
+		createdAt: now,
+		endTime:   now.Add(s.SessionLength),
+	}
+	u.sessions = append(u.sessions, sess)
+
+	writeData(w, actionData(sess, slackID))
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, u *user, slackID string) {
+	sess := u.active()
+	if sess == nil {
+		writeError(w, http.StatusBadRequest, "You don't have an active session")
+		return
+	}
+
+	now := s.Now()
+	if sess.paused {
+		sess.endTime = sess.endTime.Add(now.Sub(sess.pausedAt))
+		sess.paused = false
+	} else {
+		sess.pausedAt = now
+		sess.paused = true
+	}
+
+	writeData(w, actionData(sess, slackID))
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, u *user, slackID string) {
+	sess := u.active()
+	if sess == nil {
+		writeError(w, http.StatusBadRequest, "You don't have an active session")
+		return
+	}
+
+	now := s.Now()
+	if sess.paused {
+		sess.endTime = sess.endTime.Add(now.Sub(sess.pausedAt))
+		sess.paused = false
+	}
+	sess.endedAt = now
+	sess.cancelled = true
+
+	writeData(w, actionData(sess, slackID))
+}
+
+// actionData is the answer of the start, pause and cancel endpoints
+func actionData(sess *session, slackID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        sess.id,
+		"slackId":   slackID,
+		"createdAt": sess.createdAt,
+		"paused":    sess.paused,
+	}
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok":   true,
+		"data": data,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"ok":    false,
+		"error": message,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
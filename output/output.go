@@ -0,0 +1,339 @@
+// Package output renders typed API values as json, yaml, table or plain text.
+// Struct fields are emitted in declaration order under their json names so
+// every format is stable between runs.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Supported output formats
+const (
+	JSON  = "json"
+	YAML  = "yaml"
+	Table = "table"
+	Plain = "plain"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{JSON, YAML, Table, Plain}
+
+// Validate checks that format is a supported output format
+func Validate(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// Write renders value to w in the given format
+func Write(w io.Writer, format string, value interface{}) error {
+	switch format {
+	case JSON:
+		return writeJSON(w, value)
+	case YAML:
+		return writeYAML(w, reflect.ValueOf(value), 0)
+	case Table:
+		return writeTable(w, reflect.ValueOf(value))
+	case Plain, "":
+		return writePlain(w, reflect.ValueOf(value))
+	default:
+		return Validate(format)
+	}
+}
+
+// WriteRecord renders one record of a stream to w. JSON records are written
+// compactly on a single line, so the stream is newline-delimited JSON.
+func WriteRecord(w io.Writer, format string, value interface{}) error {
+	if format != JSON {
+		return Write(w, format, value)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// structField is an exported struct field under its json name
+type structField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// structFields returns the exported fields of a struct type
+func structFields(t reflect.Type) []structField {
+	var result []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		f := structField{name: sf.Name, index: i}
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				f.name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					f.omitEmpty = true
+				}
+			}
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// field is a named struct field value
+type field struct {
+	name  string
+	value reflect.Value
+}
+
+// fields returns the field values of a struct, leaving out empty omitempty fields
+func fields(v reflect.Value) []field {
+	var result []field
+	for _, sf := range structFields(v.Type()) {
+		fv := v.Field(sf.index)
+		if sf.omitEmpty && fv.IsZero() {
+			continue
+		}
+		result = append(result, field{name: sf.name, value: fv})
+	}
+	return result
+}
+
+// indirect dereferences pointers and interfaces
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isTime reports whether v holds a time.Time
+func isTime(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == reflect.TypeOf(time.Time{})
+}
+
+// isScalar reports whether v is rendered as a single value
+func isScalar(v reflect.Value) bool {
+	if !v.IsValid() || isTime(v) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return false
+	}
+	return true
+}
+
+// scalar formats a single value as text
+func scalar(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if isTime(v) {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// cell formats a value for a single table cell or plain line. Values that
+// are not scalars, such as maps and nested lists, are rendered as json.
+func cell(v reflect.Value) string {
+	if isScalar(v) {
+		return scalar(v)
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// yamlScalar formats a single value as a yaml scalar
+func yamlScalar(v reflect.Value) string {
+	if !v.IsValid() {
+		return "null"
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Struct:
+		return strconv.Quote(scalar(v))
+	}
+	return scalar(v)
+}
+
+func writeYAML(w io.Writer, v reflect.Value, depth int) error {
+	v = indirect(v)
+	indent := strings.Repeat("  ", depth)
+
+	if isScalar(v) {
+		_, err := fmt.Fprintf(w, "%s%s\n", indent, yamlScalar(v))
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range fields(v) {
+			fv := indirect(f.value)
+			if isScalar(fv) {
+				if _, err := fmt.Fprintf(w, "%s%s: %s\n", indent, f.name, yamlScalar(fv)); err != nil {
+					return err
+				}
+				continue
+			}
+			if fv.Kind() == reflect.Slice && fv.Len() == 0 {
+				if _, err := fmt.Fprintf(w, "%s%s: []\n", indent, f.name); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s%s:\n", indent, f.name); err != nil {
+				return err
+			}
+			if err := writeYAML(w, fv, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			_, err := fmt.Fprintf(w, "%s[]\n", indent)
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			item := indirect(v.Index(i))
+			if isScalar(item) {
+				if _, err := fmt.Fprintf(w, "%s- %s\n", indent, yamlScalar(item)); err != nil {
+					return err
+				}
+				continue
+			}
+			// Render the item one level deeper and turn its first indent into the list marker
+			var sb strings.Builder
+			if err := writeYAML(&sb, item, depth+1); err != nil {
+				return err
+			}
+			text := sb.String()
+			text = indent + "- " + strings.TrimPrefix(text, indent+"  ")
+			if _, err := io.WriteString(w, text); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Maps are only used for free-form values, render them as json
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s%s\n", indent, data)
+		return err
+	}
+	return nil
+}
+
+func writeTable(w io.Writer, v reflect.Value) error {
+	v = indirect(v)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	switch {
+	case isScalar(v):
+		fmt.Fprintln(tw, scalar(v))
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if v.Len() == 0 {
+			return nil
+		}
+		first := indirect(v.Index(0))
+		if first.Kind() != reflect.Struct || isTime(first) {
+			for i := 0; i < v.Len(); i++ {
+				fmt.Fprintln(tw, cell(indirect(v.Index(i))))
+			}
+			break
+		}
+		header := structFields(first.Type())
+		names := make([]string, len(header))
+		for i, f := range header {
+			names[i] = strings.ToUpper(f.name)
+		}
+		fmt.Fprintln(tw, strings.Join(names, "\t"))
+		for i := 0; i < v.Len(); i++ {
+			row := make([]string, len(header))
+			item := indirect(v.Index(i))
+			for j, f := range header {
+				row[j] = cell(indirect(item.Field(f.index)))
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	case v.Kind() == reflect.Struct:
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		for _, f := range fields(v) {
+			fmt.Fprintf(tw, "%s\t%s\n", f.name, cell(indirect(f.value)))
+		}
+	default:
+		fmt.Fprintln(tw, cell(v))
+	}
+	return tw.Flush()
+}
+
+// writePlain prints "key": value lines, with a blank line after each record
+func writePlain(w io.Writer, v reflect.Value) error {
+	v = indirect(v)
+
+	switch {
+	case isScalar(v):
+		_, err := fmt.Fprintln(w, scalar(v))
+		return err
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := writePlain(w, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Struct:
+		var sb strings.Builder
+		for _, f := range fields(v) {
+			sb.WriteString(fmt.Sprintf("\"%s\": %s\n", f.name, cell(indirect(f.value))))
+		}
+		_, err := fmt.Fprintln(w, sb.String())
+		return err
+	default:
+		_, err := fmt.Fprintln(w, cell(v))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Tags   []string          `json:"tags"`
+	Nested []item            `json:"nested"`
+}
+
+type item struct {
+	ID int `json:"id"`
+}
+
+func TestNestedValues(t *testing.T) {
+	value := record{
+		Name:   "robot",
+		Labels: map[string]string{"a": "1"},
+		Tags:   []string{"x", "y"},
+		Nested: []item{{ID: 1}},
+	}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{Plain, []string{`"labels": {"a":"1"}`, `"tags": ["x","y"]`, `"nested": [{"id":1}]`}},
+		{Table, []string{`labels  {"a":"1"}`, `tags    ["x","y"]`, `nested  [{"id":1}]`}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, tt.format, value); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		out := buf.String()
+		if strings.Contains(out, "map[") {
+			t.Errorf("%s output contains Go syntax:\n%s", tt.format, out)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s output lacks %q:\n%s", tt.format, want, out)
+			}
+		}
+	}
+}
+
+func TestTableRowsWithNestedValues(t *testing.T) {
+	var buf bytes.Buffer
+	rows := []record{{Name: "robot", Labels: map[string]string{"a": "1"}}}
+	if err := Write(&buf, Table, rows); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, `{"a":"1"}`) || strings.Contains(out, "map[") {
+		t.Errorf("table output:\n%s", out)
+	}
+}
+
+func TestWriteRecord(t *testing.T) {
+	var buf bytes.Buffer
+	for _, value := range []record{{Name: "one", Tags: []string{"x"}}, {Name: "two"}} {
+		if err := WriteRecord(&buf, JSON, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		`{"name":"one","labels":null,"tags":["x"],"nested":null}`,
+		`{"name":"two","labels":null,"tags":null,"nested":null}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("records = %q, want one per line", buf.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %s, want %s", i+1, lines[i], want[i])
+		}
+	}
+
+	buf.Reset()
+	if err := WriteRecord(&buf, YAML, record{Name: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `name: "one"`) {
+		t.Errorf("yaml record = %q", buf.String())
+	}
+}
@@ -0,0 +1,322 @@
+// Package queue keeps session actions that could not reach the API in a
+// durable local queue so they can be replayed once the network is back.
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"att/client"
+	"att/utils"
+)
+
+// Kinds of queued actions
+const (
+	Start  = "start"
+	Pause  = "pause"
+	Cancel = "cancel"
+)
+
+// Action is a queued session action. It is only replayed for the profile,
+// Slack ID and API base URL it was queued for.
+type Action struct {
+	ID        int       `json:"id"`
+	Kind      string    `json:"kind"`
+	Work      string    `json:"work,omitempty"`
+	Profile   string    `json:"profile"`
+	SlackID   string    `json:"slackId"`
+	BaseURL   string    `json:"baseUrl"`
+	QueuedAt  time.Time `json:"queuedAt"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// matches reports whether the action was queued for the profile and the
+// account and server of the client
+func (a Action) matches(profile string, c *client.Client) bool {
+	return a.Profile == profile && a.SlackID == c.SlackID && a.BaseURL == c.BaseURL
+}
+
+// MaxAge is the age after which a queued action is no longer replayed. A
+// session started later than that would not be the one that was meant.
+var MaxAge = time.Hour
+
+// Result is the outcome of replaying a queued action
+type Result struct {
+	ID     int    `json:"id"`
+	Kind   string `json:"kind"`
+	Work   string `json:"work,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Replay statuses
+const (
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	StatusPending = "pending"
+	StatusExpired = "expired"
+)
+
+// file is the on-disk form of the queue
+type file struct {
+	NextID  int      `json:"nextId"`
+	Actions []Action `json:"actions"`
+}
+
+// Path returns the path of the queue file in the att data directory
+func Path() (string, error) {
+	return utils.DataFilePath("queue.json", "att_queue.json")
+}
+
+func load(path string) (*file, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &file{NextID: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read queue: %w", err)
+	}
+
+	var q file
+	if err := json.Unmarshal(data, &q); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal queue: %w", err)
+	}
+	if q.NextID < 1 {
+		q.NextID = 1
+	}
+	return &q, nil
+}
+
+func save(path string, q *file) error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal queue: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write queue: %w", err)
+	}
+	return nil
+}
+
+// locked runs fn on the queue while holding the queue lock, so the CLI and
+// the daemon never replay the same action twice. The queue is saved when fn
+// reports a change.
+func locked(fn func(q *file) (bool, error)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	unlock, err := utils.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	q, err := load(path)
+	if err != nil {
+		return err
+	}
+	changed, err := fn(q)
+	if changed {
+		if saveErr := save(path, q); saveErr != nil {
+			return saveErr
+		}
+	}
+	return err
+}
+
+// List returns the pending actions in the order they were queued
+func List() ([]Action, error) {
+	var actions []Action
+	err := locked(func(q *file) (bool, error) {
+		actions = q.Actions
+		return false, nil
+	})
+	return actions, err
+}
+
+// Add appends an action for the profile and the account and server of the
+// client to the queue and returns it with its ID
+func Add(kind, work, profile string, c *client.Client) (Action, error) {
+	var action Action
+	err := locked(func(q *file) (bool, error) {
+		action = Action{
+			ID:       q.NextID,
+			Kind:     kind,
+			Work:     work,
+			Profile:  profile,
+			SlackID:  c.SlackID,
+			BaseURL:  c.BaseURL,
+			QueuedAt: time.Now(),
+		}
+		q.NextID++
+		q.Actions = append(q.Actions, action)
+		return true, nil
+	})
+	return action, err
+}
+
+// Drop removes the actions with the given IDs, or every action when no ID
+// is given. It returns the number of removed actions.
+func Drop(ids ...int) (int, error) {
+	dropped := 0
+	err := locked(func(q *file) (bool, error) {
+		if len(ids) == 0 {
+			dropped = len(q.Actions)
+			q.Actions = nil
+			return true, nil
+		}
+
+		drop := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			drop[id] = true
+		}
+		var kept []Action
+		for _, action := range q.Actions {
+			if !drop[action.ID] {
+				kept = append(kept, action)
+			}
+		}
+		dropped = len(q.Actions) - len(kept)
+		q.Actions = kept
+		return true, nil
+	})
+	return dropped, err
+}
+
+// Pending reports whether actions are queued for the profile and the
+// account and server of the client
+func Pending(profile string, c *client.Client) bool {
+	actions, err := List()
+	if err != nil {
+		return false
+	}
+	for _, action := range actions {
+		if action.matches(profile, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether a replay error may succeed on a later attempt
+func retryable(err error) bool {
+	return errors.Is(err, client.ErrNetwork) ||
+		errors.Is(err, client.ErrServer) ||
+		errors.Is(err, client.ErrUnauthorized) ||
+		errors.Is(err, client.ErrNotConfigured)
+}
+
+// Flush replays the queued actions of the profile and the client's account
+// and server in order. It stops at the first action that may succeed later,
+// such as when the API is still unreachable, and returns that error.
+// Actions rejected by the API are removed and reported as failed.
+//
+// Actions older than MaxAge are removed and reported as expired instead of
+// being replayed, and so are the pauses and cancels queued after an expired
+// start, which were meant for the session it would have started.
+//
+// The queue lock is only held to read and update the queue, so List and Add
+// are not blocked by the network. A separate replay lock keeps the CLI and
+// the daemon from replaying the same action twice.
+func Flush(c *client.Client, profile string) ([]Result, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := utils.LockFile(path + ".replay")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var actions []Action
+	err = locked(func(q *file) (bool, error) {
+		for _, action := range q.Actions {
+			if action.matches(profile, c) {
+				actions = append(actions, action)
+			}
+		}
+		return false, nil
+	})
+	if err != nil || len(actions) == 0 {
+		return nil, err
+	}
+
+	var results []Result
+	var stopErr error
+	staleSession := false
+	for _, action := range actions {
+		result := Result{ID: action.ID, Kind: action.Kind, Work: action.Work, Status: StatusDone}
+		if action.Kind == Start {
+			staleSession = time.Since(action.QueuedAt) > MaxAge
+		}
+		if staleSession || time.Since(action.QueuedAt) > MaxAge {
+			result.Status = StatusExpired
+			results = append(results, result)
+			continue
+		}
+
+		err := replay(c, action)
+		switch {
+		case err == nil:
+		case retryable(err):
+			result.Status = StatusPending
+			result.Error = err.Error()
+			stopErr = err
+		default:
+			result.Status = StatusFailed
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		if stopErr != nil {
+			break
+		}
+	}
+
+	byID := make(map[int]Result, len(results))
+	for _, result := range results {
+		byID[result.ID] = result
+	}
+	err = locked(func(q *file) (bool, error) {
+		var kept []Action
+		for _, action := range q.Actions {
+			result, replayed := byID[action.ID]
+			switch {
+			case !replayed:
+				kept = append(kept, action)
+			case result.Status == StatusPending:
+				action.Attempts++
+				action.LastError = result.Error
+				kept = append(kept, action)
+			}
+		}
+		q.Actions = kept
+		return true, nil
+	})
+	if err != nil {
+		return results, err
+	}
+	return results, stopErr
+}
+
+// replay sends a single queued action to the API
+func replay(c *client.Client, action Action) error {
+	var err error
+	switch action.Kind {
+	case Start:
+		_, err = c.Start(action.Work)
+	case Pause:
+		_, err = c.Pause()
+	case Cancel:
+		_, err = c.Cancel()
+	default:
+		err = fmt.Errorf("unknown queued action %q", action.Kind)
+	}
+	return err
+}
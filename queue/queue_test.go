@@ -0,0 +1,196 @@
+package queue
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"att/client"
+	"att/testenv"
+)
+
+// setQueue replaces the queued actions, so tests can pick their age
+func setQueue(t *testing.T, actions []Action) {
+	t.Helper()
+	err := locked(func(q *file) (bool, error) {
+		q.Actions = actions
+		q.NextID = len(actions) + 1
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ids returns the IDs of actions
+func ids(actions []Action) []int {
+	result := []int{}
+	for _, action := range actions {
+		result = append(result, action.ID)
+	}
+	return result
+}
+
+func TestAddAndPending(t *testing.T) {
+	server := testenv.Serve(t, nil)
+	c := client.New(server.URL, testenv.Token, "U1")
+
+	if Pending("default", c) {
+		t.Fatal("Pending of an empty queue")
+	}
+	for i, kind := range []string{Start, Pause, Cancel} {
+		action, err := Add(kind, "", "default", c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if action.ID != i+1 || action.SlackID != "U1" || action.BaseURL != server.URL || action.QueuedAt.IsZero() {
+			t.Errorf("Add(%s) = %+v", kind, action)
+		}
+	}
+	actions, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(actions); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("List() IDs = %v, want [1 2 3]", got)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		c       *client.Client
+		want    bool
+	}{
+		{"same profile and account", "default", c, true},
+		{"other profile", "work", c, false},
+		{"other Slack ID", "default", client.New(server.URL, testenv.Token, "U2"), false},
+		{"other base URL", "default", client.New("http://example.invalid", testenv.Token, "U1"), false},
+	}
+	for _, tt := range tests {
+		if got := Pending(tt.profile, tt.c); got != tt.want {
+			t.Errorf("%s: Pending() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	fresh := time.Now().Add(-time.Minute)
+	old := time.Now().Add(-2 * MaxAge)
+
+	tests := []struct {
+		name       string
+		actions    func(baseURL string) []Action
+		want       []string
+		wantQueued []int
+	}{
+		{
+			name: "replays in order",
+			actions: func(baseURL string) []Action {
+				return []Action{
+					{ID: 1, Kind: Start, Work: "make robot", Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 2, Kind: Pause, Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 3, Kind: Cancel, Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+				}
+			},
+			want:       []string{StatusDone, StatusDone, StatusDone},
+			wantQueued: []int{},
+		},
+		{
+			name: "drops rejected actions",
+			actions: func(baseURL string) []Action {
+				return []Action{
+					{ID: 1, Kind: Start, Work: "make robot", Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 2, Kind: Start, Work: "make another", Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 3, Kind: Cancel, Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+				}
+			},
+			want:       []string{StatusDone, StatusFailed, StatusDone},
+			wantQueued: []int{},
+		},
+		{
+			name: "keeps actions of other profiles and accounts",
+			actions: func(baseURL string) []Action {
+				return []Action{
+					{ID: 1, Kind: Start, Work: "other profile", Profile: "work", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 2, Kind: Start, Work: "other account", Profile: "default", SlackID: "U2", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 3, Kind: Start, Work: "other server", Profile: "default", SlackID: "U1", BaseURL: "http://example.invalid", QueuedAt: fresh},
+					{ID: 4, Kind: Start, Work: "make robot", Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+				}
+			},
+			want:       []string{StatusDone},
+			wantQueued: []int{1, 2, 3},
+		},
+		{
+			name: "expires an old start and the actions meant for its session",
+			actions: func(baseURL string) []Action {
+				return []Action{
+					{ID: 1, Kind: Start, Work: "make robot", Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: old},
+					{ID: 2, Kind: Pause, Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+					{ID: 3, Kind: Start, Work: "make another", Profile: "default", SlackID: "U1", BaseURL: baseURL, QueuedAt: fresh},
+				}
+			},
+			want:       []string{StatusExpired, StatusExpired, StatusDone},
+			wantQueued: []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testenv.Serve(t, nil)
+			c := client.New(server.URL, testenv.Token, "U1")
+			setQueue(t, tt.actions(server.URL))
+
+			results, err := Flush(c, "default")
+			if err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			statuses := []string{}
+			for _, result := range results {
+				statuses = append(statuses, result.Status)
+			}
+			if !reflect.DeepEqual(statuses, tt.want) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.want)
+			}
+			actions, err := List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(actions); !reflect.DeepEqual(got, tt.wantQueued) {
+				t.Errorf("queued IDs = %v, want %v", got, tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestFlushKeepsActionsOnNetworkError(t *testing.T) {
+	testenv.Isolate(t)
+	server := httptest.NewServer(nil)
+	server.Close()
+	c := client.New(server.URL, testenv.Token, "U1")
+
+	for _, kind := range []string{Start, Cancel} {
+		if _, err := Add(kind, "make robot", "default", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		results, err := Flush(c, "default")
+		if !errors.Is(err, client.ErrNetwork) {
+			t.Fatalf("Flush error = %v, want a network error", err)
+		}
+		if len(results) != 1 || results[0].ID != 1 || results[0].Status != StatusPending {
+			t.Errorf("results = %+v, want action 1 pending", results)
+		}
+		actions, err := List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(actions); !reflect.DeepEqual(got, []int{1, 2}) {
+			t.Fatalf("queued IDs = %v, want [1 2]", got)
+		}
+		if actions[0].Attempts != attempt || actions[0].LastError == "" || actions[1].Attempts != 0 {
+			t.Errorf("attempt %d: actions = %+v", attempt, actions)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"att/fsutil"
+)
+
+// FileStore keeps secrets in a file encrypted with AES-GCM. The key is a
+// random 256-bit key kept in a separate file next to it, both readable by
+// the owner only. It protects the tokens from casual reads and accidental
+// sharing of the config file, not from someone with access to the account.
+type FileStore struct {
+	Path    string
+	KeyPath string
+}
+
+// NewFileStore creates a file store at path, with the key at path+".key"
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path, KeyPath: path + ".key"}
+}
+
+// Name returns the backend name
+func (s *FileStore) Name() string {
+	return FileBackend
+}
+
+// key loads the encryption key, creating it on first use. The key file is
+// created exclusively, so when another process creates it first its key is
+// read instead of being replaced.
+func (s *FileStore) key() ([]byte, error) {
+	key, err := s.readKey()
+	if err == nil || !os.IsNotExist(err) {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("unable to generate secret key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.KeyPath), 0700); err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", filepath.Dir(s.KeyPath), err)
+	}
+	keyFile, err := os.OpenFile(s.KeyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return s.readKey()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create secret key: %w", err)
+	}
+	_, err = keyFile.Write([]byte(base64.StdEncoding.EncodeToString(key)))
+	if err == nil {
+		err = keyFile.Sync()
+	}
+	if closeErr := keyFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(s.KeyPath)
+		return nil, fmt.Errorf("unable to write secret key: %w", err)
+	}
+	return key, nil
+}
+
+// readKey reads the encryption key, the error satisfies os.IsNotExist when
+// there is no key yet
+func (s *FileStore) readKey() ([]byte, error) {
+	data, err := ioutil.ReadFile(s.KeyPath)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read secret key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid secret key file %s", s.KeyPath)
+	}
+	return key, nil
+}
+
+// cipher returns the AES-GCM cipher of the store
+func (s *FileStore) cipher() (cipher.AEAD, error) {
+	key, err := s.key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// load decrypts the secrets of all accounts
+func (s *FileStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read secrets: %w", err)
+	}
+
+	aead, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("secrets file %s is corrupted", s.Path)
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt secrets: %w", err)
+	}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// save encrypts and writes the secrets of all accounts
+func (s *FileStore) save(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	aead, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	data := aead.Seal(nonce, nonce, plain, nil)
+	if err := fsutil.WriteFileAtomic(s.Path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write secrets: %w", err)
+	}
+	return nil
+}
+
+// Get returns the secret of an account
+func (s *FileStore) Get(account string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// update runs fn on the secrets while holding the store lock, so concurrent
+// updates from att and attd do not lose each other's changes. The secrets
+// are saved when fn reports a change.
+func (s *FileStore) update(fn func(secrets map[string]string) bool) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return fmt.Errorf("unable to create %s: %w", filepath.Dir(s.Path), err)
+	}
+	unlock, err := fsutil.LockFile(s.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	if !fn(secrets) {
+		return nil
+	}
+	return s.save(secrets)
+}
+
+// Set stores the secret of an account
+func (s *FileStore) Set(account, value string) error {
+	return s.update(func(secrets map[string]string) bool {
+		secrets[account] = value
+		return true
+	})
+}
+
+// Delete removes the secret of an account
+func (s *FileStore) Delete(account string) error {
+	return s.update(func(secrets map[string]string) bool {
+		if _, ok := secrets[account]; !ok {
+			return false
+		}
+		delete(secrets, account)
+		return true
+	})
+}
@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "att", "secrets"))
+
+	if _, err := store.Get("default"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of an empty store error = %v, want ErrNotFound", err)
+	}
+
+	steps := []struct {
+		name    string
+		do      func() error
+		account string
+		want    string
+		wantErr error
+	}{
+		{"set", func() error { return store.Set("default", "token-1") }, "default", "token-1", nil},
+		{"set another account", func() error { return store.Set("work", "token-2") }, "default", "token-1", nil},
+		{"replace", func() error { return store.Set("default", "token-3") }, "default", "token-3", nil},
+		{"other account kept", func() error { return nil }, "work", "token-2", nil},
+		{"delete", func() error { return store.Delete("default") }, "default", "", ErrNotFound},
+		{"delete a missing account", func() error { return store.Delete("default") }, "work", "token-2", nil},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		// A new store reads everything back from the files
+		got, err := NewFileStore(store.Path).Get(step.account)
+		if got != step.want || !errors.Is(err, step.wantErr) {
+			t.Errorf("%s: Get(%s) = %q, %v, want %q, %v", step.name, step.account, got, err, step.want, step.wantErr)
+		}
+	}
+
+	data, err := ioutil.ReadFile(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("token-2")) {
+		t.Error("the secrets file holds the token in plain text")
+	}
+
+	if runtime.GOOS != "windows" {
+		for _, path := range []string{store.Path, store.KeyPath} {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm(); mode != 0600 {
+				t.Errorf("%s mode = %v, want 0600", filepath.Base(path), mode)
+			}
+		}
+		info, err := os.Stat(filepath.Dir(store.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0700 {
+			t.Errorf("directory mode = %v, want 0700", mode)
+		}
+	}
+}
+
+func TestFileStoreRejectsOtherKey(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "secrets"))
+	if err := store.Set("default", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(store.KeyPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("default"); err == nil {
+		t.Error("Get with a new key succeeded")
+	}
+}
+
+func TestFileStoreConcurrentSet(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "secrets"))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := store.Set(fmt.Sprintf("profile-%d", i), fmt.Sprintf("token-%d", i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		if got, err := store.Get(fmt.Sprintf("profile-%d", i)); err != nil || got != fmt.Sprintf("token-%d", i) {
+			t.Errorf("profile-%d = %q, %v", i, got, err)
+		}
+	}
+}
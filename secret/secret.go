@@ -0,0 +1,118 @@
+// Package secret stores API tokens outside of the plaintext config file,
+// in the freedesktop Secret Service when one is running and in an encrypted
+// file otherwise.
+package secret
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrNotFound is returned when no secret is stored for an account
+var ErrNotFound = errors.New("secret not found")
+
+// BackendEnv is the environment variable forcing a backend, either
+// "secret-service" or "file"
+const BackendEnv = "ATT_SECRET_BACKEND"
+
+// Backend names
+const (
+	SecretServiceBackend = "secret-service"
+	FileBackend          = "file"
+)
+
+// Store keeps one secret per account
+type Store interface {
+	// Name returns the backend name
+	Name() string
+	// Get returns the secret of an account or ErrNotFound
+	Get(account string) (string, error)
+	// Set stores the secret of an account, replacing any previous one
+	Set(account, value string) error
+	// Delete removes the secret of an account, it is not an error when
+	// there is none
+	Delete(account string) error
+}
+
+// Open returns the Secret Service store when it is reachable and a file
+// store at filePath otherwise. ATT_SECRET_BACKEND forces either backend.
+// Without it, secrets the Secret Service fails to store, such as when the
+// keyring stays locked, go to the file store instead.
+func Open(filePath string) (Store, error) {
+	switch os.Getenv(BackendEnv) {
+	case FileBackend:
+		return NewFileStore(filePath), nil
+	case SecretServiceBackend:
+		store, err := NewSecretServiceStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	if store, err := NewSecretServiceStore(); err == nil {
+		return &fallbackStore{primary: store, fallback: NewFileStore(filePath)}, nil
+	}
+	return NewFileStore(filePath), nil
+}
+
+// fallbackStore keeps secrets in primary, and in fallback when primary
+// fails to store them
+type fallbackStore struct {
+	primary  Store
+	fallback Store
+
+	lock     sync.Mutex
+	fellBack bool
+}
+
+// Name returns the name of the backend the last secret was stored in
+func (s *fallbackStore) Name() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.fellBack {
+		return s.fallback.Name()
+	}
+	return s.primary.Name()
+}
+
+// Get returns the secret from primary, or from fallback when primary does
+// not have it
+func (s *fallbackStore) Get(account string) (string, error) {
+	value, err := s.primary.Get(account)
+	if err == nil {
+		return value, nil
+	}
+	if value, fallbackErr := s.fallback.Get(account); fallbackErr == nil {
+		return value, nil
+	}
+	return "", err
+}
+
+// Set stores the secret in primary and removes any copy from fallback, or
+// stores it in fallback when primary fails
+func (s *fallbackStore) Set(account, value string) error {
+	err := s.primary.Set(account, value)
+	if err == nil {
+		s.fallback.Delete(account)
+		return nil
+	}
+	if s.fallback.Set(account, value) != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.fellBack = true
+	return nil
+}
+
+// Delete removes the secret from both stores
+func (s *fallbackStore) Delete(account string) error {
+	err := s.primary.Delete(account)
+	if fallbackErr := s.fallback.Delete(account); err == nil {
+		err = fallbackErr
+	}
+	return err
+}
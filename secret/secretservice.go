@@ -0,0 +1,243 @@
+//go:build linux || openbsd || netbsd
+
+package secret
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName       = "org.freedesktop.secrets"
+	servicePath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	defaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+
+	serviceInterface    = "org.freedesktop.Secret.Service"
+	collectionInterface = "org.freedesktop.Secret.Collection"
+	itemInterface       = "org.freedesktop.Secret.Item"
+	promptInterface     = "org.freedesktop.Secret.Prompt"
+
+	// application is the attribute tagging the items created by att
+	application = "att"
+)
+
+// dbusSecret is the Secret struct of the Secret Service API
+type dbusSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretServiceStore stores secrets in the default collection of the
+// freedesktop Secret Service over the D-Bus session bus
+type SecretServiceStore struct {
+	conn *dbus.Conn
+}
+
+// NewSecretServiceStore connects to the Secret Service on the session bus.
+// DBUS_SESSION_BUS_ADDRESS selects the bus as usual.
+func NewSecretServiceStore() (*SecretServiceStore, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the session bus: %w", err)
+	}
+
+	var activatable []string
+	var owned bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, serviceName).Store(&owned); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to query the session bus: %w", err)
+	}
+	if !owned {
+		if err := conn.BusObject().Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to query the session bus: %w", err)
+		}
+		found := false
+		for _, name := range activatable {
+			found = found || name == serviceName
+		}
+		if !found {
+			conn.Close()
+			return nil, fmt.Errorf("no Secret Service on the session bus")
+		}
+	}
+
+	return &SecretServiceStore{conn: conn}, nil
+}
+
+// Name returns the backend name
+func (s *SecretServiceStore) Name() string {
+	return SecretServiceBackend
+}
+
+// attributes identify the item of an account
+func attributes(account string) map[string]string {
+	return map[string]string{
+		"application": application,
+		"account":     account,
+	}
+}
+
+// openSession opens a plain transfer session, the bus is local to the user
+func (s *SecretServiceStore) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := s.conn.Object(serviceName, servicePath).
+		Call(serviceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	if err != nil {
+		return "", fmt.Errorf("unable to open a Secret Service session: %w", err)
+	}
+	return session, nil
+}
+
+// closeSession closes a transfer session
+func (s *SecretServiceStore) closeSession(session dbus.ObjectPath) {
+	s.conn.Object(serviceName, session).Call("org.freedesktop.Secret.Session.Close", 0)
+}
+
+// search returns the items of an account, unlocking them when needed
+func (s *SecretServiceStore) search(account string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := s.conn.Object(serviceName, servicePath).
+		Call(serviceInterface+".SearchItems", 0, attributes(account)).
+		Store(&unlocked, &locked)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search the Secret Service: %w", err)
+	}
+
+	if len(locked) > 0 {
+		if err := s.unlock(locked); err != nil {
+			return nil, err
+		}
+		unlocked = append(unlocked, locked...)
+	}
+	return unlocked, nil
+}
+
+// unlock unlocks objects, going through a prompt when the service asks for one
+func (s *SecretServiceStore) unlock(objects []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	err := s.conn.Object(serviceName, servicePath).
+		Call(serviceInterface+".Unlock", 0, objects).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return fmt.Errorf("unable to unlock the Secret Service: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+// prompt runs a Secret Service prompt and waits for it to complete
+func (s *SecretServiceStore) prompt(prompt dbus.ObjectPath) error {
+	if prompt == "" || prompt == "/" {
+		return nil
+	}
+
+	if err := s.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(promptInterface),
+		dbus.WithMatchMember("Completed"),
+	); err != nil {
+		return fmt.Errorf("unable to wait for the Secret Service prompt: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(serviceName, prompt).Call(promptInterface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("unable to show the Secret Service prompt: %w", err)
+	}
+
+	for signal := range signals {
+		if signal.Path != prompt || signal.Name != promptInterface+".Completed" {
+			continue
+		}
+		if dismissed, ok := signal.Body[0].(bool); ok && dismissed {
+			return fmt.Errorf("the Secret Service prompt was dismissed")
+		}
+		return nil
+	}
+	return fmt.Errorf("the session bus connection was closed")
+}
+
+// Get returns the secret of an account
+func (s *SecretServiceStore) Get(account string) (string, error) {
+	items, err := s.search(account)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", ErrNotFound
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.closeSession(session)
+
+	var secret dbusSecret
+	err = s.conn.Object(serviceName, items[0]).
+		Call(itemInterface+".GetSecret", 0, session).
+		Store(&secret)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the secret: %w", err)
+	}
+	return string(secret.Value), nil
+}
+
+// Set stores the secret of an account in the default collection
+func (s *SecretServiceStore) Set(account, value string) error {
+	if err := s.unlock([]dbus.ObjectPath{defaultCollection}); err != nil {
+		return err
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return err
+	}
+	defer s.closeSession(session)
+
+	properties := map[string]dbus.Variant{
+		itemInterface + ".Label":      dbus.MakeVariant(fmt.Sprintf("att API token (%s)", account)),
+		itemInterface + ".Attributes": dbus.MakeVariant(attributes(account)),
+	}
+	secret := dbusSecret{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       []byte(value),
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+	err = s.conn.Object(serviceName, defaultCollection).
+		Call(collectionInterface+".CreateItem", 0, properties, secret, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("unable to store the secret: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+// Delete removes the secret of an account
+func (s *SecretServiceStore) Delete(account string) error {
+	items, err := s.search(account)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := s.conn.Object(serviceName, item).Call(itemInterface+".Delete", 0).Store(&prompt); err != nil {
+			return fmt.Errorf("unable to delete the secret: %w", err)
+		}
+		if err := s.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build !(linux || openbsd || netbsd)
+
+package secret
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// errNoSecretService is returned on platforms without a Secret Service
+var errNoSecretService = fmt.Errorf("the Secret Service is not available on %s", runtime.GOOS)
+
+// SecretServiceStore is not available on this platform
+type SecretServiceStore struct{}
+
+// NewSecretServiceStore always fails on this platform, so Open uses the
+// file store
+func NewSecretServiceStore() (*SecretServiceStore, error) {
+	return nil, errNoSecretService
+}
+
+// Name returns the backend name
+func (s *SecretServiceStore) Name() string {
+	return SecretServiceBackend
+}
+
+// Get is not available on this platform
+func (s *SecretServiceStore) Get(account string) (string, error) {
+	return "", errNoSecretService
+}
+
+// Set is not available on this platform
+func (s *SecretServiceStore) Set(account, value string) error {
+	return errNoSecretService
+}
+
+// Delete is not available on this platform
+func (s *SecretServiceStore) Delete(account string) error {
+	return errNoSecretService
+}
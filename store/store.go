@@ -0,0 +1,234 @@
+// Package store keeps a local copy of the sessions, stats and goals of each
+// user so they can be queried while offline. The store file is shared by the
+// CLI and the daemon and every access holds its lock.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"att/client"
+	"att/utils"
+)
+
+// Endpoints whose data is stored, as passed to LastFetched
+const (
+	Session = "session"
+	Stats   = "stats"
+	Goals   = "goals"
+	History = "history"
+)
+
+// User is the stored data of a single Slack user. SyncedAt is the time of
+// the last full sync, FetchedAt the time each endpoint was last fetched.
+type User struct {
+	Session   *client.Session      `json:"session,omitempty"`
+	Stats     *client.Stats        `json:"stats,omitempty"`
+	Goals     client.Goals         `json:"goals,omitempty"`
+	History   client.History       `json:"history,omitempty"`
+	SyncedAt  time.Time            `json:"syncedAt"`
+	FetchedAt map[string]time.Time `json:"fetchedAt,omitempty"`
+}
+
+// LastFetched returns the time the data of an endpoint was last fetched,
+// which is zero when it never was
+func (u *User) LastFetched(endpoint string) time.Time {
+	if fetched, ok := u.FetchedAt[endpoint]; ok && fetched.After(u.SyncedAt) {
+		return fetched
+	}
+	return u.SyncedAt
+}
+
+// fetched records that the data of endpoints was fetched now
+func (u *User) fetched(endpoints ...string) {
+	if u.FetchedAt == nil {
+		u.FetchedAt = make(map[string]time.Time)
+	}
+	now := time.Now()
+	for _, endpoint := range endpoints {
+		u.FetchedAt[endpoint] = now
+	}
+}
+
+// file is the on-disk form of the store
+type file struct {
+	Users map[string]*User `json:"users"`
+}
+
+// Path returns the path of the store file in the att data directory
+func Path() (string, error) {
+	return utils.DataFilePath("store.json", "att_store.json")
+}
+
+func load(path string) (*file, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &file{Users: make(map[string]*User)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read store: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal store: %w", err)
+	}
+	if f.Users == nil {
+		f.Users = make(map[string]*User)
+	}
+	return &f, nil
+}
+
+func save(path string, f *file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal store: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write store: %w", err)
+	}
+	return nil
+}
+
+// Load returns the stored data of a Slack user. Data that was never fetched
+// has a zero LastFetched.
+func Load(slackID string) (*User, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := utils.LockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	f, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := f.Users[slackID]; ok {
+		return u, nil
+	}
+	return &User{}, nil
+}
+
+// Update applies fn to the stored data of a Slack user while holding the
+// store lock and saves the result
+func Update(slackID string, fn func(u *User) error) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	unlock, err := utils.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := load(path)
+	if err != nil {
+		return err
+	}
+	u, ok := f.Users[slackID]
+	if !ok {
+		u = &User{}
+		f.Users[slackID] = u
+	}
+	if err := fn(u); err != nil {
+		return err
+	}
+	return save(path, f)
+}
+
+// MergeHistory merges fetched history entries into the stored ones. Entries
+// are matched by their creation time, newer data replaces older data, and the
+// result is sorted oldest first. It returns the number of new entries.
+func (u *User) MergeHistory(history client.History) int {
+	known := make(map[int64]int, len(u.History))
+	for i, entry := range u.History {
+		known[entry.CreatedAt.UnixNano()] = i
+	}
+
+	added := 0
+	for _, entry := range history {
+		if i, ok := known[entry.CreatedAt.UnixNano()]; ok {
+			u.History[i] = entry
+			continue
+		}
+		known[entry.CreatedAt.UnixNano()] = len(u.History)
+		u.History = append(u.History, entry)
+		added++
+	}
+
+	sort.SliceStable(u.History, func(i, j int) bool {
+		return u.History[i].CreatedAt.Before(u.History[j].CreatedAt)
+	})
+	return added
+}
+
+// Remember merges freshly fetched API data of a Slack user into the store:
+// a *client.Session, *client.Stats, client.Goals or client.History
+func Remember(slackID string, data interface{}) error {
+	return Update(slackID, func(u *User) error {
+		switch value := data.(type) {
+		case *client.Session:
+			u.Session = value
+			u.fetched(Session)
+		case *client.Stats:
+			u.Stats = value
+			u.fetched(Stats)
+		case client.Goals:
+			u.Goals = value
+			u.fetched(Goals)
+		case client.History:
+			u.MergeHistory(value)
+			u.fetched(History)
+		}
+		return nil
+	})
+}
+
+// Sync fetches the session, stats, goals and history of the client's user
+// and merges them into the store. It returns the updated data.
+func Sync(c *client.Client) (*User, error) {
+	history, err := c.History()
+	if err != nil {
+		return nil, err
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		return nil, err
+	}
+	goals, err := c.Goals()
+	if err != nil {
+		return nil, err
+	}
+	session, err := c.Session()
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	var synced *User
+	err = Update(c.SlackID, func(u *User) error {
+		u.MergeHistory(history)
+		u.Stats = stats
+		u.Goals = goals
+		u.Session = session
+		u.fetched(Session, Stats, Goals, History)
+		u.SyncedAt = u.FetchedAt[History]
+		synced = u
+		return nil
+	})
+	return synced, err
+}
+
+// isNotFound reports whether the API answered 404, e.g. a user without sessions
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*client.APIError)
+	return ok && apiErr.StatusCode == 404
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"att/client"
+	"att/testenv"
+)
+
+func TestMergeHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 7, d, 10, 0, 0, 0, time.UTC) }
+	entry := func(d int, work string) client.HistoryEntry {
+		return client.HistoryEntry{CreatedAt: day(d), Work: work}
+	}
+	works := func(h client.History) []string {
+		result := []string{}
+		for _, entry := range h {
+			result = append(result, entry.Work)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		stored    client.History
+		fetched   client.History
+		want      []string
+		wantAdded int
+	}{
+		{"empty store", nil, client.History{entry(2, "b"), entry(1, "a")}, []string{"a", "b"}, 2},
+		{"nothing fetched", client.History{entry(1, "a")}, nil, []string{"a"}, 0},
+		{"newer data replaces older", client.History{entry(1, "a"), entry(2, "b")}, client.History{entry(2, "b2")}, []string{"a", "b2"}, 0},
+		{"new entries are sorted in", client.History{entry(1, "a"), entry(3, "c")}, client.History{entry(2, "b"), entry(4, "d")}, []string{"a", "b", "c", "d"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{History: tt.stored}
+			added := u.MergeHistory(tt.fetched)
+			if got := works(u.History); !reflect.DeepEqual(got, tt.want) || added != tt.wantAdded {
+				t.Errorf("history = %q, added %d, want %q, added %d", got, added, tt.want, tt.wantAdded)
+			}
+		})
+	}
+}
+
+func TestLoadAndUpdate(t *testing.T) {
+	testenv.Isolate(t)
+
+	u, err := Load("U1")
+	if err != nil {
+		t.Fatalf("Load of an empty store: %v", err)
+	}
+	if u.Stats != nil || !u.LastFetched(Stats).IsZero() {
+		t.Errorf("unknown user = %+v", u)
+	}
+
+	err = Update("U1", func(u *User) error {
+		u.Goals = client.Goals{{Name: "att"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if u, err = Load("U1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Goals) != 1 || u.Goals[0].Name != "att" {
+		t.Errorf("goals = %+v, want att", u.Goals)
+	}
+	if u, err = Load("U2"); err != nil || u.Goals != nil {
+		t.Errorf("other user = %+v, %v", u, err)
+	}
+
+	path, err := Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load("U1"); err == nil {
+		t.Error("Load of a corrupt store succeeded")
+	}
+}
+
+func TestLastFetched(t *testing.T) {
+	server := testenv.Serve(t, nil)
+	c := client.New(server.URL, testenv.Token, "U1")
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Remember("U1", stats); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+	u, err := Load("U1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Stats == nil || u.LastFetched(Stats).IsZero() {
+		t.Errorf("remembered stats = %+v, fetched at %v", u.Stats, u.LastFetched(Stats))
+	}
+	for _, endpoint := range []string{Session, Goals, History} {
+		if fetched := u.LastFetched(endpoint); !fetched.IsZero() {
+			t.Errorf("%s fetched at %v, want never", endpoint, fetched)
+		}
+	}
+
+	synced, err := Sync(c)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	for _, endpoint := range []string{Session, Stats, Goals, History} {
+		if fetched := synced.LastFetched(endpoint); fetched.IsZero() || fetched.Before(synced.SyncedAt) {
+			t.Errorf("%s fetched at %v, want the sync at %v", endpoint, fetched, synced.SyncedAt)
+		}
+	}
+}
@@ -2,75 +2,74 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"net"
+	"os"
+	"strings"
+	"time"
+
+	"att/ipc"
+	"att/utils"
 )
 
 func main() {
 	// Define the pipePath flag
 	var pipePath string
-	flag.StringVar(&pipePath, "pipe-path", "/tmp/attd", "set the path for the pipe")
-	flag.Parse()
+	defaultPipePath, _ := utils.SocketPath()
+	flag.StringVar(&pipePath, "pipe-path", defaultPipePath, "set the path for the pipe")
 
-	// Define which command to test: "start" or "track"
-	var command string
-	flag.StringVar(&command, "command", "track", "specify the command to send (start or track)")
-	flag.Parse()
-
-	// Prepare the data payload based on the command
-	var payload map[string]interface{}
-	if command == "start" {
-		payload = map[string]interface{}{
-			"command": "start",
-			"data": map[string]interface{}{
-				"work":     "work on att",
-				"slack_id": "xxxxxxxxx",
-				"api_key":  "xxxxxxxxx",
-			},
-		}
-	} else if command == "track" {
-		payload = map[string]interface{}{
-			"command": "track",
-			"data": map[string]interface{}{
-				"slack_id": "xxxxxxxxx",
-				"api_key":  "xxxxxxxxxxx",
-			},
-		}
-	} else {
-		fmt.Printf("Invalid command specified: %s\n", command)
-		return
-	}
+	// Define which commands to send over one connection, e.g. "flush,track"
+	var commands string
+	flag.StringVar(&commands, "command", "track", "comma-separated commands to send, e.g. start,session,pause")
 
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Printf("Failed to marshal JSON: %v\n", err)
-		return
-	}
+	// Define the request data
+	var work, profile, slackID, apiKey string
+	flag.StringVar(&work, "work", "work on att", "set the work description of start")
+	flag.StringVar(&profile, "profile", "", "set the att config profile to use instead of the one of attd")
+	flag.StringVar(&slackID, "slack-id", "", "set the Slack ID overriding the profile")
+	flag.StringVar(&apiKey, "api-key", "", "set the API key overriding the profile")
+	flag.Parse()
 
 	// Connect to the Unix domain socket
-	conn, err := net.Dial("unix", pipePath)
+	c, err := ipc.Dial(pipePath, 5*time.Second)
 	if err != nil {
 		fmt.Printf("Failed to connect to socket: %v\n", err)
-		return
+		os.Exit(1)
 	}
-	defer conn.Close()
+	defer c.Close()
 
-	// Send the JSON payload
-	_, err = conn.Write(jsonPayload)
-	if err != nil {
-		fmt.Printf("Failed to write to socket: %v\n", err)
-		return
-	}
+	failed := false
+	for _, command := range strings.Split(commands, ",") {
+		data := map[string]interface{}{}
+		if profile != "" {
+			data["profile"] = profile
+		}
+		if slackID != "" {
+			data["slack_id"] = slackID
+		}
+		if apiKey != "" {
+			data["api_key"] = apiKey
+		}
+		if command == "start" {
+			data["work"] = work
+		}
 
-	// Receive the response
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
-	if err != nil {
-		fmt.Printf("Failed to read from socket: %v\n", err)
-		return
+		// Send the request and print its result
+		var result json.RawMessage
+		if err := c.Call(command, data, &result); err != nil {
+			failed = true
+			var e *ipc.Error
+			if errors.As(err, &e) {
+				fmt.Printf("%s failed (%s): %s\n", command, e.Code, e.Message)
+				continue
+			}
+			fmt.Printf("%s failed: %v\n", command, err)
+			break
+		}
+		fmt.Printf("%s: %s\n", command, result)
+	}
+	if failed {
+		os.Exit(1)
 	}
-
-	response := string(buf[:n])
-	fmt.Printf("Received response: %s\n", response)
 }
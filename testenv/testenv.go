@@ -0,0 +1,38 @@
+// Package testenv gives tests private att directories and a mock Hack Hour
+// API, so they never touch the real config, secrets or state of the user.
+package testenv
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"att/mockserver"
+)
+
+// Token is the API token the mock servers of Serve accept
+const Token = "test-token"
+
+// Isolate points HOME and the XDG directories at a temporary directory for
+// the duration of the test and selects the file secret store
+func Isolate(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_RUNTIME_DIR"} {
+		t.Setenv(env, dir)
+	}
+	t.Setenv("ATT_SECRET_BACKEND", "file")
+	return dir
+}
+
+// Serve isolates the test and serves mock for its duration. A nil mock is
+// a new mock server accepting Token.
+func Serve(t testing.TB, mock *mockserver.Server) *httptest.Server {
+	t.Helper()
+	Isolate(t)
+	if mock == nil {
+		mock = mockserver.New(Token)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return server
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useConfigFile points ConfigPath at a file in a temporary directory that
+// holds data, unless data is empty
+func useConfigFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if data != "" {
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldConfigPath := ConfigPath
+	t.Cleanup(func() { ConfigPath = oldConfigPath })
+	ConfigPath = path
+	return path
+}
+
+func TestLoadConfigFileMigrates(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantProfiles map[string]map[string]string
+		wantBackup   bool
+	}{
+		{"missing file", "", map[string]map[string]string{}, false},
+		{"flat legacy file", `{"slack-id":"U1","base-url":"http://localhost"}`, map[string]map[string]string{DefaultProfile: {"slack-id": "U1", "base-url": "http://localhost"}}, true},
+		{"empty legacy file", `{}`, map[string]map[string]string{}, true},
+		{"unversioned profiles", `{"profiles":{"work":{"slack-id":"U2"}}}`, map[string]map[string]string{"work": {"slack-id": "U2"}}, true},
+		{"current file", `{"version":1,"profiles":{"work":{"slack-id":"U2"}}}`, map[string]map[string]string{"work": {"slack-id": "U2"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useConfigFile(t, tt.data)
+			config, err := LoadConfigFile()
+			if err != nil {
+				t.Fatalf("LoadConfigFile: %v", err)
+			}
+			if config.Version != ConfigVersion || !reflect.DeepEqual(config.Profiles, tt.wantProfiles) {
+				t.Errorf("config = version %d, profiles %v, want %v", config.Version, config.Profiles, tt.wantProfiles)
+			}
+
+			backup, err := ioutil.ReadFile(path + ".bak")
+			if tt.wantBackup {
+				if err != nil || string(backup) != tt.data {
+					t.Errorf("backup = %q, %v, want the old file", backup, err)
+				}
+				if info, err := os.Stat(path + ".bak"); err == nil && info.Mode().Perm()&0077 != 0 {
+					t.Errorf("backup mode = %v, want readable by the owner only", info.Mode().Perm())
+				}
+				// The migrated file is current and loads without another backup
+				saved, _, err := readConfigFile(path)
+				if err != nil || saved.Version != ConfigVersion || !reflect.DeepEqual(saved.Profiles, tt.wantProfiles) {
+					t.Errorf("migrated file = %+v, %v", saved, err)
+				}
+			} else if !os.IsNotExist(err) {
+				t.Errorf("unexpected backup %q, %v", backup, err)
+			}
+		})
+	}
+}
+
+func TestMigrationKeepsExistingBackup(t *testing.T) {
+	path := useConfigFile(t, `{"slack-id":"U1"}`)
+	if err := ioutil.WriteFile(path+".bak", []byte("older"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFile(); err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	if backup, _ := ioutil.ReadFile(path + ".bak"); string(backup) != "older" {
+		t.Errorf("backup = %q, want the existing one kept", backup)
+	}
+}
+
+func TestLoadConfigFileValidates(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantErr      string
+		wantWarnings []string
+	}{
+		{"valid", `{"version":1,"notifications":{"schedule":["10m"]},"output":{"format":"json"},"hooks":{"start":"true"}}`, "", nil},
+		{"newer version", `{"version":2}`, "newer", nil},
+		{"bad schedule", `{"version":1,"notifications":{"schedule":["soon"]}}`, "notifications.schedule", nil},
+		{"bad output format", `{"version":1,"output":{"format":"xml"}}`, "output.format", nil},
+		{"blank hook", `{"version":1,"hooks":{"pause":"  "}}`, "hooks.pause", nil},
+		{"unknown keys", `{"version":1,"colour":"red","hooks":{"resume":"true"},"profiles":{"work":{"token":"x"}}}`, "", []string{
+			`unknown config key "colour"`,
+			`unknown config key "hooks.resume"`,
+			`unknown config key "profiles.work.token"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigFile(t, tt.data)
+			config, err := LoadConfigFile()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want one about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfigFile: %v", err)
+			}
+			if !reflect.DeepEqual(config.Warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", config.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
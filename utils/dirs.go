@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// appDir is the directory of att below each base directory
+const appDir = "att"
+
+// ConfigDir returns the directory of the config file and the secrets,
+// $XDG_CONFIG_HOME/att
+func ConfigDir() (string, error) {
+	return baseDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the directory of the local session store and the offline
+// queue, $XDG_DATA_HOME/att
+func DataDir() (string, error) {
+	return baseDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// StateDir returns the directory of the logs and the daemon state,
+// $XDG_STATE_HOME/att
+func StateDir() (string, error) {
+	return baseDir("XDG_STATE_HOME", ".local", "state")
+}
+
+// RuntimeDir returns the directory of the daemon socket, $XDG_RUNTIME_DIR/att.
+// Without XDG_RUNTIME_DIR a directory private to the user in the temporary
+// directory is used. It is created when missing, and refused unless it
+// belongs to the user, is not a symlink and has mode 0700.
+func RuntimeDir() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.TempDir(), appDir), nil
+	}
+	dir := filepath.Join(os.TempDir(), appDir+"-"+strconv.Itoa(os.Getuid()))
+	if err := ensurePrivateDir(dir); err != nil {
+		return "", fmt.Errorf("unsafe runtime directory, set XDG_RUNTIME_DIR: %w", err)
+	}
+	return dir, nil
+}
+
+// SocketPath returns the default path of the daemon socket
+func SocketPath() (string, error) {
+	dir, err := RuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "attd.sock"), nil
+}
+
+// PIDFilePath returns the default path of the daemon pid file
+func PIDFilePath() (string, error) {
+	dir, err := RuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "attd.pid"), nil
+}
+
+// DataFilePath returns the path of a file in DataDir, moving a file that
+// older versions kept in the user config directory there first. DataDir is
+// created when missing.
+func DataFilePath(name, legacyName string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, name)
+	if err := MigrateLegacyFile(legacyName, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// baseDir returns the att directory below the base directory named by env.
+// Without the variable it is below home on Unix-like systems, and in the
+// user config directory on Windows and macOS.
+func baseDir(env string, home ...string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to get user config directory: %w", err)
+		}
+		return filepath.Join(dir, appDir), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get home directory: %w", err)
+	}
+	return filepath.Join(append(append([]string{homeDir}, home...), appDir)...), nil
+}
+
+// MigrateLegacyFile moves a file that older versions kept in the user
+// config directory to path, unless path already exists. Its lock file is
+// removed.
+func MigrateLegacyFile(legacyName, path string) error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	legacyPath := filepath.Join(configDir, legacyName)
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := moveFile(legacyPath, path); err != nil {
+		return fmt.Errorf("unable to move %s to %s: %w", legacyPath, path, err)
+	}
+	os.Remove(legacyPath + ".lock")
+	return nil
+}
+
+// moveFile renames a file, copying it when the rename crosses file systems
+func moveFile(from, to string) error {
+	err := os.Rename(from, to)
+	var linkErr *os.LinkError
+	if err == nil || !errors.As(err, &linkErr) || linkErr.Err != syscall.EXDEV {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(to, data, 0600); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestRuntimeDirFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fallback is the user's own temporary directory on Windows")
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(dir string) error
+		wantErr bool
+	}{
+		{"created when missing", func(dir string) error { return nil }, false},
+		{"private directory", func(dir string) error { return os.Mkdir(dir, 0700) }, false},
+		{"readable by others", func(dir string) error {
+			if err := os.Mkdir(dir, 0700); err != nil {
+				return err
+			}
+			return os.Chmod(dir, 0755)
+		}, true},
+		{"symlink", func(dir string) error {
+			target := filepath.Join(filepath.Dir(dir), "target")
+			if err := os.Mkdir(target, 0700); err != nil {
+				return err
+			}
+			return os.Symlink(target, dir)
+		}, true},
+		{"file", func(dir string) error { return ioutil.WriteFile(dir, nil, 0600) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			t.Setenv("XDG_RUNTIME_DIR", "")
+			want := filepath.Join(tmp, "att-"+strconv.Itoa(os.Getuid()))
+			if err := tt.setup(want); err != nil {
+				t.Fatal(err)
+			}
+
+			dir, err := RuntimeDir()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RuntimeDir() = %q, %v, wantErr %v", dir, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if dir != want {
+				t.Errorf("RuntimeDir() = %q, want %q", dir, want)
+			}
+			if info, err := os.Lstat(dir); err != nil || !info.IsDir() || info.Mode().Perm() != 0700 {
+				t.Errorf("runtime directory = %v, %v, want a 0700 directory", info.Mode(), err)
+			}
+		})
+	}
+}
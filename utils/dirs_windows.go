@@ -0,0 +1,9 @@
+//go:build windows
+
+package utils
+
+// ensurePrivateDir is not used on Windows, where the temporary directory
+// belongs to the user
+func ensurePrivateDir(dir string) error {
+	return nil
+}
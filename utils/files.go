@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"os"
+
+	"att/fsutil"
+)
+
+// WriteFileAtomic replaces path with data so readers never see a partial
+// file, see fsutil.WriteFileAtomic
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return fsutil.WriteFileAtomic(path, data, perm)
+}
+
+// LockFile takes an exclusive advisory lock on path+".lock" and returns the
+// function releasing it, see fsutil.LockFile
+func LockFile(path string) (func() error, error) {
+	return fsutil.LockFile(path)
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default request settings, see RequestTimeout and MaxRetries
+const (
+	DefaultRequestTimeout = 15 * time.Second
+	DefaultMaxRetries     = 3
+)
+
+// RequestTimeout bounds a single attempt of an API request
+var RequestTimeout = DefaultRequestTimeout
+
+// MaxRetries is the number of retries after a failed attempt
+var MaxRetries = DefaultMaxRetries
+
+// RetryBaseDelay and RetryMaxDelay bound the exponential backoff between attempts
+var (
+	RetryBaseDelay = 500 * time.Millisecond
+	RetryMaxDelay  = 30 * time.Second
+)
+
+// jitter randomizes the backoff delays
+var (
+	jitterMu sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// httpClient is shared by the CLI and the daemon so connections are reused
+var httpClient = &http.Client{Transport: http.DefaultTransport}
+
+// MakeAPIRequest makes an API request and returns the response.
+//
+// Idempotent requests (GET, HEAD) are retried on network errors and on
+// 429, 502, 503 and 504 responses. Other requests are only retried when the
+// server can not have acted on them: the connection could not be opened, or
+// the server answered 429 or 503. Retry-After is honoured when present.
+func MakeAPIRequest(method string, url string, payload []byte, apiToken string) (*http.Response, error) {
+	idempotent := method == http.MethodGet || method == http.MethodHead
+
+	for attempt := 0; ; attempt++ {
+		resp, err := doRequest(method, url, payload, apiToken)
+
+		retry := false
+		var delay time.Duration
+		if err != nil {
+			retry = idempotent || isDialError(err)
+		} else {
+			switch resp.StatusCode {
+			case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+				retry = true
+			case http.StatusBadGateway, http.StatusGatewayTimeout:
+				retry = idempotent
+			}
+			if retry {
+				delay = retryAfter(resp)
+			}
+		}
+
+		if !retry || attempt >= MaxRetries {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if delay <= 0 {
+			delay = backoff(attempt)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// doRequest performs a single attempt of an API request
+func doRequest(method string, url string, payload []byte, apiToken string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+	}
+
+	client := *httpClient
+	client.Timeout = RequestTimeout
+	return client.Do(req)
+}
+
+// isDialError reports whether err happened before the request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfter parses the Retry-After header as seconds or an HTTP date
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = time.Until(date)
+	}
+
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	return delay
+}
+
+// backoff returns an exponential delay with full jitter for the given attempt
+func backoff(attempt int) time.Duration {
+	delay := RetryBaseDelay << uint(attempt)
+	if delay <= 0 || delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return time.Duration(jitter.Int63n(int64(delay))) + time.Millisecond
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"att/mockserver"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, 500 * time.Millisecond},
+		{1, time.Second},
+		{3, 4 * time.Second},
+		{6, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			delay := backoff(tt.attempt)
+			if delay <= 0 || delay > tt.max+time.Millisecond {
+				t.Fatalf("backoff(%d) = %s, want in (0, %s]", tt.attempt, delay, tt.max+time.Millisecond)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		min, max time.Duration
+	}{
+		{"missing", "", 0, 0},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second},
+		{"zero", "0", 0, 0},
+		{"capped", "120", RetryMaxDelay, RetryMaxDelay},
+		{"date", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{"far date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), RetryMaxDelay, RetryMaxDelay},
+		{"past date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), -2 * time.Hour, 0},
+		{"invalid", "soon", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+			if delay := retryAfter(resp); delay < tt.min || delay > tt.max {
+				t.Errorf("retryAfter(%q) = %s, want in [%s, %s]", tt.value, delay, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// flakyServer answers the first failures requests with status and passes
+// the others on to a mock server. It counts all requests.
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	t.Helper()
+	mock := mockserver.New("test-token")
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestMakeAPIRequestRetries(t *testing.T) {
+	oldBase, oldRetries := RetryBaseDelay, MaxRetries
+	t.Cleanup(func() {
+		RetryBaseDelay, MaxRetries = oldBase, oldRetries
+	})
+	RetryBaseDelay, MaxRetries = time.Millisecond, 3
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		failures     int32
+		status       int
+		wantStatus   int
+		wantRequests int32
+	}{
+		{"get recovers from 503", http.MethodGet, "/api/session/U1", 2, http.StatusServiceUnavailable, http.StatusNotFound, 3},
+		{"get recovers from 502", http.MethodGet, "/api/session/U1", 1, http.StatusBadGateway, http.StatusNotFound, 2},
+		{"post recovers from 429", http.MethodPost, "/api/start/U1", 2, http.StatusTooManyRequests, http.StatusOK, 3},
+		{"post does not retry 502", http.MethodPost, "/api/start/U1", 1, http.StatusBadGateway, http.StatusBadGateway, 1},
+		{"get gives up", http.MethodGet, "/api/session/U1", 10, http.StatusServiceUnavailable, http.StatusServiceUnavailable, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := flakyServer(t, tt.failures, tt.status)
+			resp, err := MakeAPIRequest(tt.method, server.URL+tt.path, []byte(`{"work":"make robot"}`), "test-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := atomic.LoadInt32(requests); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValidateHTTPAddr checks that an address of the attd HTTP API only listens
+// on the loopback interface
+func ValidateHTTPAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if port == "" {
+		return fmt.Errorf("invalid address %q: the port is missing", addr)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("address %q is not on localhost", addr)
+	}
+	return nil
+}
+
+// HTTPTokenPath returns the path of the bearer token of the attd HTTP API,
+// which lives next to the config file
+func HTTPTokenPath() (string, error) {
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configFilePath), "http-token"), nil
+}
+
+// ReadHTTPToken returns the bearer token of the attd HTTP API without
+// creating it. The error satisfies os.IsNotExist when there is none yet.
+func ReadHTTPToken() (string, error) {
+	path, err := HTTPTokenPath()
+	if err != nil {
+		return "", err
+	}
+	return readHTTPToken(path)
+}
+
+func readHTTPToken(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read the HTTP API token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("the HTTP API token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// HTTPToken returns the bearer token of the attd HTTP API, creating it when
+// there is none. Creating it holds a lock on the token file, so att and attd
+// starting at the same time agree on one token.
+func HTTPToken() (string, error) {
+	return withHTTPTokenLock(func(path string) (string, error) {
+		token, err := readHTTPToken(path)
+		if !os.IsNotExist(err) {
+			return token, err
+		}
+		return writeHTTPToken(path)
+	})
+}
+
+// RotateHTTPToken replaces the bearer token of the attd HTTP API with a new
+// random one and returns it
+func RotateHTTPToken() (string, error) {
+	return withHTTPTokenLock(writeHTTPToken)
+}
+
+// withHTTPTokenLock runs fn with the path of the token file while holding
+// its lock
+func withHTTPTokenLock(fn func(path string) (string, error)) (string, error) {
+	path, err := HTTPTokenPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("unable to create %s: %w", filepath.Dir(path), err)
+	}
+	unlock, err := LockFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	return fn(path)
+}
+
+// writeHTTPToken saves a new random token at path and returns it
+func writeHTTPToken(path string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("unable to generate the HTTP API token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if err := WriteFileAtomic(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("unable to save the HTTP API token: %w", err)
+	}
+	return token, nil
+}
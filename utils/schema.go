@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"att/output"
+)
+
+// ConfigVersion is the version of the config file format written by att.
+// Files without a version are migrated on first load.
+const ConfigVersion = 1
+
+// ProfileKeys lists the keys of a profile
+var ProfileKeys = []string{"api-token", "slack-id", "base-url"}
+
+// NotificationSettings configures the reminders sent by attd
+type NotificationSettings struct {
+	// Schedule lists the remaining times at which a reminder is sent, for
+	// example "20m" or "5m". The built-in schedule is used when empty.
+	Schedule []string `json:"schedule,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// DaemonSettings configures attd
+type DaemonSettings struct {
+	Socket string `json:"socket,omitempty"`
+	// HTTP is the localhost address of the HTTP API of attd, off when empty
+	HTTP string `json:"http,omitempty"`
+}
+
+// OutputSettings holds output defaults of the CLI
+type OutputSettings struct {
+	Format string `json:"format,omitempty"`
+}
+
+// HookSettings holds shell commands run after successful session actions
+type HookSettings struct {
+	Start  string `json:"start,omitempty"`
+	Pause  string `json:"pause,omitempty"`
+	Cancel string `json:"cancel,omitempty"`
+}
+
+// Command returns the hook command of a session action kind
+func (h HookSettings) Command(kind string) string {
+	switch kind {
+	case "start":
+		return h.Start
+	case "pause":
+		return h.Pause
+	case "cancel":
+		return h.Cancel
+	}
+	return ""
+}
+
+// Validate checks that every hook that is set has a command to run
+func (h HookSettings) Validate() error {
+	for _, kind := range []string{"start", "pause", "cancel"} {
+		command := h.Command(kind)
+		if command == "" {
+			continue
+		}
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("hooks.%s: the command is blank", kind)
+		}
+		if strings.ContainsRune(command, 0) {
+			return fmt.Errorf("hooks.%s: the command contains a NUL byte", kind)
+		}
+	}
+	return nil
+}
+
+// ScheduleDurations returns the parsed notification schedule, longest first
+func (n NotificationSettings) ScheduleDurations() ([]time.Duration, error) {
+	durations := make([]time.Duration, 0, len(n.Schedule))
+	for _, spec := range n.Schedule {
+		d, err := time.ParseDuration(spec)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("notifications.schedule: %q is not a positive duration", spec)
+		}
+		durations = append(durations, d)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] > durations[j] })
+	return durations, nil
+}
+
+// Validate checks the settings of the configuration
+func (c *ConfigFile) Validate() error {
+	if c.Version > ConfigVersion {
+		return fmt.Errorf("config file version %d is newer than this version of att supports (%d)", c.Version, ConfigVersion)
+	}
+	for name := range c.Profiles {
+		if name == "" {
+			return fmt.Errorf("profiles: a profile has an empty name")
+		}
+	}
+	if _, err := c.Notifications.ScheduleDurations(); err != nil {
+		return err
+	}
+	if c.Daemon.HTTP != "" {
+		if err := ValidateHTTPAddr(c.Daemon.HTTP); err != nil {
+			return fmt.Errorf("daemon.http: %w", err)
+		}
+	}
+	if c.Output.Format != "" {
+		if err := output.Validate(c.Output.Format); err != nil {
+			return fmt.Errorf("output.format: %w", err)
+		}
+	}
+	return c.Hooks.Validate()
+}
+
+// unknownKeys lists the keys of a JSON object that the config schema does
+// not know, as dotted paths
+func unknownKeys(data []byte) []string {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+
+	unknown := unknownStructKeys("", raw, reflect.TypeOf(ConfigFile{}))
+	var profiles map[string]map[string]json.RawMessage
+	if json.Unmarshal(raw["profiles"], &profiles) == nil {
+		for name, profile := range profiles {
+			for key := range profile {
+				if !isProfileKey(key) {
+					unknown = append(unknown, "profiles."+name+"."+key)
+				}
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// unknownStructKeys compares the keys of an object with the json names of
+// the fields of a struct type, descending into nested structs
+func unknownStructKeys(prefix string, raw map[string]json.RawMessage, t reflect.Type) []string {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	var unknown []string
+	for key, value := range raw {
+		fieldType, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		if fieldType.Kind() != reflect.Struct {
+			continue
+		}
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(value, &nested) == nil {
+			unknown = append(unknown, unknownStructKeys(prefix+key+".", nested, fieldType)...)
+		}
+	}
+	return unknown
+}
+
+// isProfileKey reports whether key is one of ProfileKeys
+func isProfileKey(key string) bool {
+	for _, k := range ProfileKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
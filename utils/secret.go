@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"att/secret"
+)
+
+var (
+	secretOnce     sync.Once
+	secretStore    secret.Store
+	secretStoreErr error
+)
+
+// SecretStore returns the store holding the API tokens of the profiles,
+// opened on first use. The encrypted file lives next to the config file.
+//
+// The Secret Service keys its items by profile name only, so a config given
+// with --config always uses the file, and its profiles never share tokens
+// with the profiles of the same name in the default config.
+func SecretStore() (secret.Store, error) {
+	secretOnce.Do(func() {
+		configFilePath, err := ConfigFilePath()
+		if err != nil {
+			secretStoreErr = err
+			return
+		}
+		secretsPath := filepath.Join(filepath.Dir(configFilePath), "secrets")
+		if ConfigPath != "" {
+			if os.Getenv(secret.BackendEnv) == secret.SecretServiceBackend {
+				secretStoreErr = fmt.Errorf("%s=%s does not work with --config, its tokens are kept in %s", secret.BackendEnv, secret.SecretServiceBackend, secretsPath)
+				return
+			}
+			secretStore = secret.NewFileStore(secretsPath)
+			return
+		}
+		for _, suffix := range []string{"", ".key"} {
+			if err := MigrateLegacyFile("att_secrets"+suffix, secretsPath+suffix); err != nil {
+				secretStoreErr = err
+				return
+			}
+		}
+		secretStore, secretStoreErr = secret.Open(secretsPath)
+	})
+	return secretStore, secretStoreErr
+}
+
+// withToken returns a copy of the configuration data of a profile with the
+// API token from the secret store. A token still kept in plaintext wins.
+// The origin of every value is recorded in origins when it is not nil.
+func withToken(name string, configData map[string]string, origins map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(configData)+1)
+	for key, value := range configData {
+		result[key] = value
+		if origins != nil {
+			origins[key] = OriginProfile + " " + name
+		}
+	}
+	if result["api-token"] != "" {
+		return result, nil
+	}
+
+	store, err := SecretStore()
+	if err != nil {
+		return nil, err
+	}
+	token, err := store.Get(name)
+	if errors.Is(err, secret.ErrNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the API token from the %s store: %w", store.Name(), err)
+	}
+	result["api-token"] = token
+	if origins != nil {
+		origins["api-token"] = store.Name() + " store"
+	}
+	return result, nil
+}
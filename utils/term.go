@@ -0,0 +1,24 @@
+package utils
+
+import "os"
+
+// IsTerminal reports whether f is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	_, err := getTermMode(f)
+	return err == nil
+}
+
+// DisableEcho stops the terminal f from echoing typed input, for reading
+// secrets. Call the returned function to restore the previous mode.
+func DisableEcho(f *os.File) (func() error, error) {
+	mode, err := getTermMode(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := setTermMode(f, withoutEcho(mode)); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return setTermMode(f, mode)
+	}, nil
+}
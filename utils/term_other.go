@@ -0,0 +1,26 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+// errNoTerminal is returned where terminal modes are not supported, so
+// IsTerminal always reports false there
+var errNoTerminal = errors.New("terminal modes are not supported on this platform")
+
+type termMode struct{}
+
+func getTermMode(f *os.File) (termMode, error) {
+	return termMode{}, errNoTerminal
+}
+
+func setTermMode(f *os.File, mode termMode) error {
+	return errNoTerminal
+}
+
+func withoutEcho(mode termMode) termMode {
+	return mode
+}
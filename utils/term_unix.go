@@ -0,0 +1,34 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package utils
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type termMode syscall.Termios
+
+func getTermMode(f *os.File) (termMode, error) {
+	var mode termMode
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&mode)))
+	if errno != 0 {
+		return mode, errno
+	}
+	return mode, nil
+}
+
+func setTermMode(f *os.File, mode termMode) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&mode)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func withoutEcho(mode termMode) termMode {
+	mode.Lflag &^= syscall.ECHO
+	mode.Lflag |= syscall.ICANON | syscall.ISIG
+	return mode
+}
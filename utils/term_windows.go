@@ -0,0 +1,35 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+const enableEchoInput = 0x4
+
+type termMode uint32
+
+func getTermMode(f *os.File) (termMode, error) {
+	var mode uint32
+	err := syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode)
+	return termMode(mode), err
+}
+
+func setTermMode(f *os.File, mode termMode) error {
+	r, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func withoutEcho(mode termMode) termMode {
+	return mode &^ enableEchoInput
+}
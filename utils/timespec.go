@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the absolute time formats accepted by ParseTimeSpec
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// relativeUnits are the units of relative time specs
+var relativeUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseTimeSpec parses an absolute time such as "2024-07-01" or
+// "2024-07-01T15:04:05Z", or a time relative to now such as "7d" (7 days
+// ago). Supported relative units are m, h, d and w. Absolute times without
+// a zone are read in local time.
+func ParseTimeSpec(spec string, now time.Time) (time.Time, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return time.Time{}, nil
+	}
+
+	if len(spec) > 1 {
+		if unit, ok := relativeUnits[spec[len(spec)-1:]]; ok {
+			if n, err := strconv.Atoi(spec[:len(spec)-1]); err == nil && n >= 0 {
+				return now.Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, spec, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected a date like 2006-01-02 or a relative time like 7d)", spec)
+}
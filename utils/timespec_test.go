@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeSpec(t *testing.T) {
+	now := time.Date(2024, 7, 10, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"  ", time.Time{}, false},
+		{"0d", now, false},
+		{"30m", now.Add(-30 * time.Minute), false},
+		{"2h", now.Add(-2 * time.Hour), false},
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"2w", now.AddDate(0, 0, -14), false},
+		{" 1d ", now.AddDate(0, 0, -1), false},
+		{"2024-07-01T15:04:05Z", time.Date(2024, 7, 1, 15, 4, 5, 0, time.UTC), false},
+		{"2024-07-01T15:04:05+02:00", time.Date(2024, 7, 1, 13, 4, 5, 0, time.UTC), false},
+		{"2024-07-01T15:04", time.Date(2024, 7, 1, 15, 4, 0, 0, time.Local), false},
+		{"2024-07-01 15:04", time.Date(2024, 7, 1, 15, 4, 0, 0, time.Local), false},
+		{"2024-07-01", time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local), false},
+		{"d", time.Time{}, true},
+		{"-1d", time.Time{}, true},
+		{"7y", time.Time{}, true},
+		{"1.5h", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+		{"07/01/2024", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeSpec(tt.spec, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeSpec(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
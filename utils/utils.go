@@ -1,50 +1,408 @@
 package utils
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
-// HandleError prints and exits on error
-func HandleError(message string, err error) {
+// DefaultBaseURL is the address of the public Hack Hour API
+const DefaultBaseURL = "https://hackhour.hackclub.com"
+
+// BaseURLEnv is the environment variable overriding the API base URL
+const BaseURLEnv = "ATT_BASE_URL"
+
+// DefaultProfile is the profile used when none is selected
+const DefaultProfile = "default"
+
+// ProfileEnv is the environment variable selecting the profile
+const ProfileEnv = "ATT_PROFILE"
+
+// Profile selects the profile when set, it is bound to the --profile flag
+var Profile string
+
+// Environment variables overriding the credentials of the active profile
+const (
+	APITokenEnv = "ATT_API_TOKEN"
+	SlackIDEnv  = "ATT_SLACK_ID"
+)
+
+// Overrides bound to the --config, --token and --slack-id flags
+var (
+	ConfigPath   string
+	APITokenFlag string
+	SlackIDFlag  string
+)
+
+// Origins of configuration values, see LoadConfigDataWithOrigin
+const (
+	OriginFlag    = "flag"
+	OriginEnv     = "env"
+	OriginProfile = "profile"
+	OriginDefault = "default"
+)
+
+// ConfigFile is the content of the configuration file: the settings of
+// each named profile, the profile in use and the settings shared by all
+// profiles
+type ConfigFile struct {
+	Version        int                          `json:"version"`
+	CurrentProfile string                       `json:"current-profile,omitempty"`
+	Profiles       map[string]map[string]string `json:"profiles"`
+	Notifications  NotificationSettings         `json:"notifications"`
+	Daemon         DaemonSettings               `json:"daemon"`
+	Output         OutputSettings               `json:"output"`
+	Hooks          HookSettings                 `json:"hooks"`
+
+	// Warnings lists the problems found while loading that are not fatal
+	Warnings []string `json:"-"`
+	// Backup is the copy of the file made when it was migrated by this load
+	Backup string `json:"-"`
+}
+
+// ConfigFilePath returns the path of the configuration file in ConfigDir,
+// which the --config flag overrides
+func ConfigFilePath() (string, error) {
+	if ConfigPath != "" {
+		return ConfigPath, nil
+	}
+
+	configDir, err := ConfigDir()
 	if err != nil {
-		fmt.Println(message, err)
-		os.Exit(1)
+		return "", err
+	}
+	configFilePath := filepath.Join(configDir, "config.json")
+	for _, suffix := range []string{"", ".bak"} {
+		if err := MigrateLegacyFile("att_config.json"+suffix, configFilePath+suffix); err != nil {
+			return "", err
+		}
 	}
+	return configFilePath, nil
 }
 
-// LoadConfigData loads the configuration data from a file
-func LoadConfigData() map[string]string {
-	configDir, err := os.UserConfigDir()
-	HandleError("Unable to get user config directory", err)
+// LoadConfigFile loads and validates the configuration file. A missing
+// file yields an empty configuration. Files written by older versions are
+// migrated to ConfigVersion, keeping a backup of the old file.
+func LoadConfigFile() (*ConfigFile, error) {
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	config, legacy, err := readConfigFile(configFilePath)
+	if err != nil || !legacy {
+		return config, err
+	}
+
+	// Migrate under the lock since another process may be migrating too
+	var migrated *ConfigFile
+	err = UpdateConfigFile(func(c *ConfigFile) error {
+		migrated = c
+		return nil
+	})
+	if err != nil {
+		config.Warnings = append(config.Warnings, fmt.Sprintf("unable to migrate the config file: %v", err))
+		return config, nil
+	}
+	return migrated, nil
+}
 
-	configFilePath := filepath.Join(configDir, "att_config.json")
+// readConfigFile reads a configuration file and reports whether it was
+// written by an older version. Legacy files are converted in memory: a flat
+// file becomes the default profile.
+func readConfigFile(configFilePath string) (*ConfigFile, bool, error) {
+	config := &ConfigFile{Version: ConfigVersion, Profiles: make(map[string]map[string]string)}
 	configBytes, err := ioutil.ReadFile(configFilePath)
+	if os.IsNotExist(err) {
+		return config, false, nil
+	}
 	if err != nil {
-		return make(map[string]string)
+		return nil, false, fmt.Errorf("unable to read config file: %w", err)
 	}
 
-	var configData map[string]string
-	err = json.Unmarshal(configBytes, &configData)
-	HandleError("Unable to unmarshal config data", err)
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(configBytes, &raw); err != nil {
+		return nil, false, fmt.Errorf("unable to unmarshal config data: %w", err)
+	}
 
-	return configData
+	_, versioned := raw["version"]
+	_, profiles := raw["profiles"]
+	if !versioned && !profiles {
+		var flat map[string]string
+		if err := json.Unmarshal(configBytes, &flat); err != nil {
+			return nil, false, fmt.Errorf("unable to unmarshal config data: %w", err)
+		}
+		if len(flat) > 0 {
+			config.Profiles[DefaultProfile] = flat
+		}
+		return config, true, nil
+	}
+
+	config.Version = 0
+	if err := json.Unmarshal(configBytes, config); err != nil {
+		return nil, false, fmt.Errorf("unable to unmarshal config data: %w", err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]map[string]string)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, false, fmt.Errorf("invalid config file %s: %w", configFilePath, err)
+	}
+	for _, key := range unknownKeys(configBytes) {
+		config.Warnings = append(config.Warnings, fmt.Sprintf("unknown config key %q", key))
+	}
+
+	legacy := !versioned
+	config.Version = ConfigVersion
+	return config, legacy, nil
+}
+
+// backupConfigFile copies a configuration file before it is migrated. An
+// existing backup is kept.
+func backupConfigFile(configFilePath string) (string, error) {
+	backupPath := configFilePath + ".bak"
+	if _, err := os.Stat(backupPath); err == nil {
+		return backupPath, nil
+	}
+	configBytes, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read config file: %w", err)
+	}
+	if err := WriteFileAtomic(backupPath, configBytes, 0600); err != nil {
+		return "", fmt.Errorf("unable to back up config file: %w", err)
+	}
+	return backupPath, nil
+}
+
+// SaveConfigFile writes the configuration file atomically, readable by the
+// user only. Use UpdateConfigFile for read-modify-write changes.
+func SaveConfigFile(config *ConfigFile) error {
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configFilePath), 0700); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+
+	configBytes, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal config data: %w", err)
+	}
+	if err := WriteFileAtomic(configFilePath, configBytes, 0600); err != nil {
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	return nil
 }
 
-// MakeAPIRequest makes an API request and returns the response
-func MakeAPIRequest(method string, url string, payload []byte, apiToken string) (*http.Response, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
+// UpdateConfigFile loads the configuration file, lets fn change it and saves
+// it while holding the lock of the file, so concurrent updates are not lost.
+// Nothing is saved when fn fails. Legacy files are backed up and migrated.
+func UpdateConfigFile(fn func(config *ConfigFile) error) error {
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configFilePath), 0700); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+
+	unlock, err := LockFile(configFilePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, legacy, err := readConfigFile(configFilePath)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		if config.Backup, err = backupConfigFile(configFilePath); err != nil {
+			return err
+		}
+	}
+	if err := fn(config); err != nil {
+		return err
+	}
+	return SaveConfigFile(config)
+}
+
+// ActiveProfile returns the selected profile. The --profile flag wins over
+// the ATT_PROFILE environment variable, which wins over the profile chosen
+// with "att configure profile use".
+func (c *ConfigFile) ActiveProfile() string {
+	if Profile != "" {
+		return Profile
+	}
+	if env := os.Getenv(ProfileEnv); env != "" {
+		return env
+	}
+	if c.CurrentProfile != "" {
+		return c.CurrentProfile
+	}
+	return DefaultProfile
+}
+
+// ProfileNames returns the names of the configured profiles in order
+func (c *ConfigFile) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileData returns the configuration data of a named profile as stored
+// in the file, without the API token kept in the secret store. The default
+// profile always exists, even when it was never configured.
+func (c *ConfigFile) ProfileData(name string) (map[string]string, error) {
+	data, ok := c.Profiles[name]
+	if !ok {
+		if name != DefaultProfile {
+			return nil, fmt.Errorf("profile %q does not exist", name)
+		}
+		data = make(map[string]string)
+	}
+	return data, nil
+}
+
+// LoadProfileData loads the configuration data of a named profile,
+// including its API token from the secret store
+func LoadProfileData(name string) (map[string]string, error) {
+	config, err := LoadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	configData, err := config.ProfileData(name)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+	return withToken(name, configData, nil)
+}
+
+// LoadConfigData loads the effective configuration data of the active
+// profile, see LoadConfigDataWithOrigin
+func LoadConfigData() (map[string]string, error) {
+	configData, _, err := LoadConfigDataWithOrigin()
+	return configData, err
+}
+
+// LoadConfigDataWithOrigin loads the effective configuration data of the
+// active profile and where each value came from. The API token and Slack
+// ID are taken from the --token and --slack-id flags, then the
+// ATT_API_TOKEN and ATT_SLACK_ID environment variables, then the profile.
+func LoadConfigDataWithOrigin() (map[string]string, map[string]string, error) {
+	config, err := LoadConfigFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	name := config.ActiveProfile()
+	configData, err := config.ProfileData(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overrides := []struct {
+		key, flag, env string
+	}{
+		{"api-token", APITokenFlag, APITokenEnv},
+		{"slack-id", SlackIDFlag, SlackIDEnv},
+	}
+
+	// Skip the secret store when the token is overridden anyway
+	origins := make(map[string]string)
+	if APITokenFlag != "" || os.Getenv(APITokenEnv) != "" {
+		configData = copyConfigData(configData)
+		for key := range configData {
+			origins[key] = OriginProfile + " " + name
+		}
+	} else if configData, err = withToken(name, configData, origins); err != nil {
+		return nil, nil, err
+	}
+
+	for _, o := range overrides {
+		if value := os.Getenv(o.env); value != "" {
+			configData[o.key] = value
+			origins[o.key] = OriginEnv + " " + o.env
+		}
+		if o.flag != "" {
+			configData[o.key] = o.flag
+			origins[o.key] = OriginFlag
+		}
+	}
+	return configData, origins, nil
+}
+
+// CredentialsOverridden reports whether the API token or Slack ID comes
+// from the --token and --slack-id flags or the ATT_API_TOKEN and
+// ATT_SLACK_ID environment variables instead of the profile
+func CredentialsOverridden() bool {
+	return APITokenFlag != "" || SlackIDFlag != "" ||
+		os.Getenv(APITokenEnv) != "" || os.Getenv(SlackIDEnv) != ""
+}
+
+// copyConfigData returns a copy of configuration data
+func copyConfigData(configData map[string]string) map[string]string {
+	result := make(map[string]string, len(configData))
+	for key, value := range configData {
+		result[key] = value
+	}
+	return result
+}
+
+// ResolveBaseURL returns the API base URL of the active profile, see
+// ProfileBaseURL
+func ResolveBaseURL(flagValue string) (string, error) {
+	baseURL, _, err := ResolveBaseURLWithOrigin(flagValue)
+	return baseURL, err
+}
+
+// ResolveBaseURLWithOrigin returns the API base URL of the active profile
+// and where it came from
+func ResolveBaseURLWithOrigin(flagValue string) (string, string, error) {
+	if flagValue != "" || os.Getenv(BaseURLEnv) != "" {
+		baseURL, origin := profileBaseURL(flagValue, nil, "")
+		return baseURL, origin, nil
+	}
+
+	config, err := LoadConfigFile()
+	if err != nil {
+		return "", "", err
+	}
+	name := config.ActiveProfile()
+	configData, err := config.ProfileData(name)
+	if err != nil {
+		return "", "", err
+	}
+	baseURL, origin := profileBaseURL(flagValue, configData, name)
+	return baseURL, origin, nil
+}
+
+// ProfileBaseURL returns the API base URL for the configuration data of a
+// profile. The flag value wins over the ATT_BASE_URL environment variable,
+// which wins over the "base-url" config key. DefaultBaseURL is used when
+// none of them is set.
+func ProfileBaseURL(flagValue string, configData map[string]string) string {
+	baseURL, _ := profileBaseURL(flagValue, configData, "")
+	return baseURL
+}
 
-	return client.Do(req)
+func profileBaseURL(flagValue string, configData map[string]string, name string) (string, string) {
+	baseURL, origin := flagValue, OriginFlag
+	if baseURL == "" {
+		baseURL, origin = os.Getenv(BaseURLEnv), OriginEnv+" "+BaseURLEnv
+	}
+	if baseURL == "" {
+		baseURL, origin = configData["base-url"], OriginProfile+" "+name
+	}
+	if baseURL == "" {
+		baseURL, origin = DefaultBaseURL, OriginDefault
+	}
+	return strings.TrimRight(baseURL, "/"), origin
 }